@@ -3,15 +3,24 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+
+	"travel_guide/utils/validate"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DBConfig     DBConfig
-	OSSConfig    OSSConfig
-	ServerConfig ServerConfig
-	JWTConfig    JWTConfig
+	DBConfig      DBConfig
+	OSSConfig     OSSConfig
+	StorageConfig StorageConfig
+	ImageConfig   ImageConfig
+	ServerConfig  ServerConfig
+	JWTConfig     JWTConfig
+	UserConfig    UserConfig
+	OAuthConfig   OAuthConfig
+	NotifyConfig  NotifyConfig
+	SeedConfig    SeedConfig
 }
 
 type DBConfig struct {
@@ -29,13 +38,95 @@ type OSSConfig struct {
 	BucketName      string
 }
 
+// StorageConfig 文件存储配置，Driver 为 local、oss 或 s3
+type StorageConfig struct {
+	Driver         string
+	LocalDir       string // local：文件存放目录
+	LocalURLPrefix string // local：静态路由路径
+	PublicURL      string // 文件访问地址前缀，为空时按存储方式自动生成
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKeyID  string
+	S3SecretKey    string
+	S3PathStyle    bool
+}
+
+// ImageConfig 上传图片的限制
+type ImageConfig struct {
+	MaxBytes      int64 // 单张图片最大字节数
+	MaxPixels     int64 // 单张图片最大像素数（宽×高）
+	StripMetadata bool  // 是否去除 EXIF 等元数据，默认开启以保护用户位置隐私
+
+	DirectUploadExpiresIn int64 // 直传地址有效期（秒）
+}
+
 type ServerConfig struct {
 	Port int
 }
 
 type JWTConfig struct {
-	SecretKey string
-	ExpiresIn int64
+	SecretKey        string
+	ExpiresIn        int64 // 访问令牌有效期（秒）
+	RefreshExpiresIn int64 // 刷新令牌有效期（秒）
+}
+
+type UserConfig struct {
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	ReservedUsernames     []string // 不允许注册的用户名，不区分大小写
+	TOTPIssuer            string   // 身份验证器中显示的应用名称
+	TwoFactorRoles        []string // 必须启用两步验证才能使用其权限的角色
+}
+
+// PasswordPolicy 注册、修改密码和创建管理员时共用的密码强度要求
+func (c UserConfig) PasswordPolicy() validate.PasswordPolicy {
+	return validate.PasswordPolicy{
+		MinLength:     c.PasswordMinLength,
+		RequireUpper:  c.PasswordRequireUpper,
+		RequireLower:  c.PasswordRequireLower,
+		RequireDigit:  c.PasswordRequireDigit,
+		RequireSymbol: c.PasswordRequireSymbol,
+	}
+}
+
+// SeedConfig 演示数据和首个管理员的配置
+type SeedConfig struct {
+	Env           string // 运行环境，决定默认加载哪个演示数据文件
+	Enabled       bool   // 是否导入演示数据，生产环境应保持关闭
+	FixtureFile   string // 演示数据文件，为空时使用 FixtureDir/<Env>.json
+	FixtureDir    string
+	AdminUsername string // 数据库中还没有管理员时用于创建首个管理员
+	AdminPassword string
+	AdminEmail    string
+}
+
+// OAuthProviderConfig 一个 OpenID Connect 登录方式的配置
+type OAuthProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // 前端回调页面地址，需要与提供方登记的一致
+	Scopes       []string
+}
+
+type OAuthConfig struct {
+	Providers []OAuthProviderConfig
+}
+
+type NotifyConfig struct {
+	EmailDriver  string // log 或 smtp
+	LogFile      string // log 驱动写入的文件，为空时输出到标准输出
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	FrontendURL  string // 邮件中验证、重置链接指向的前端地址
 }
 
 var AppConfig Config
@@ -70,11 +161,90 @@ func LoadConfig() error {
 		Port: serverPort,
 	}
 
+	// 文件存储配置，默认保存到本地目录，不依赖外部服务
+	AppConfig.StorageConfig = StorageConfig{
+		Driver:         getEnv("STORAGE_DRIVER", "local"),
+		LocalDir:       getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		LocalURLPrefix: getEnv("STORAGE_LOCAL_URL_PREFIX", "/uploads"),
+		PublicURL:      getEnv("STORAGE_PUBLIC_URL", ""),
+		S3Endpoint:     getEnv("S3_ENDPOINT", ""),
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:  getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretKey:    getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:    getEnv("S3_PATH_STYLE", "true") == "true",
+	}
+
+	// 图片上传限制
+	imageMaxBytes, _ := strconv.ParseInt(getEnv("IMAGE_MAX_BYTES", "10485760"), 10, 64)
+	imageMaxPixels, _ := strconv.ParseInt(getEnv("IMAGE_MAX_PIXELS", "40000000"), 10, 64)
+	directUploadExpiresIn, _ := strconv.ParseInt(getEnv("IMAGE_DIRECT_UPLOAD_EXPIRES_IN", "900"), 10, 64)
+	AppConfig.ImageConfig = ImageConfig{
+		MaxBytes:      imageMaxBytes,
+		MaxPixels:     imageMaxPixels,
+		StripMetadata: getEnv("IMAGE_STRIP_METADATA", "true") == "true",
+
+		DirectUploadExpiresIn: directUploadExpiresIn,
+	}
+
 	// JWT配置
-	expiresIn, _ := strconv.ParseInt(getEnv("JWT_EXPIRES_IN", "86400"), 10, 64)
+	expiresIn, _ := strconv.ParseInt(getEnv("JWT_EXPIRES_IN", "900"), 10, 64)
+	refreshExpiresIn, _ := strconv.ParseInt(getEnv("JWT_REFRESH_EXPIRES_IN", "2592000"), 10, 64)
 	AppConfig.JWTConfig = JWTConfig{
-		SecretKey: getEnv("JWT_SECRET_KEY", ""),
-		ExpiresIn: expiresIn,
+		SecretKey:        getEnv("JWT_SECRET_KEY", ""),
+		ExpiresIn:        expiresIn,
+		RefreshExpiresIn: refreshExpiresIn,
+	}
+
+	// 注册校验配置
+	passwordMinLength, _ := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8"))
+	AppConfig.UserConfig = UserConfig{
+		PasswordMinLength:     passwordMinLength,
+		PasswordRequireUpper:  getEnv("PASSWORD_REQUIRE_UPPER", "false") == "true",
+		PasswordRequireLower:  getEnv("PASSWORD_REQUIRE_LOWER", "true") == "true",
+		PasswordRequireDigit:  getEnv("PASSWORD_REQUIRE_DIGIT", "true") == "true",
+		PasswordRequireSymbol: getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+		ReservedUsernames:     splitList(getEnv("RESERVED_USERNAMES", "admin,administrator,root,system,moderator,support,api,null")),
+		TOTPIssuer:            getEnv("TOTP_ISSUER", "TravelGuide"),
+		TwoFactorRoles:        splitList(getEnv("TWO_FACTOR_REQUIRED_ROLES", "admin")),
+	}
+
+	// 演示数据和首个管理员，默认不导入任何用户
+	AppConfig.SeedConfig = SeedConfig{
+		Env:           getEnv("APP_ENV", "development"),
+		Enabled:       getEnv("SEED_ENABLED", "false") == "true",
+		FixtureFile:   getEnv("SEED_FIXTURE", ""),
+		FixtureDir:    getEnv("SEED_FIXTURE_DIR", "seed/fixtures"),
+		AdminUsername: getEnv("ADMIN_USERNAME", ""),
+		AdminPassword: getEnv("ADMIN_PASSWORD", ""),
+		AdminEmail:    getEnv("ADMIN_EMAIL", ""),
+	}
+
+	// 第三方登录配置，OAUTH_PROVIDERS 列出启用的提供方，每个提供方读取 OAUTH_<NAME>_* 变量
+	AppConfig.OAuthConfig = OAuthConfig{}
+	for _, name := range splitList(getEnv("OAUTH_PROVIDERS", "")) {
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		AppConfig.OAuthConfig.Providers = append(AppConfig.OAuthConfig.Providers, OAuthProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid profile email")),
+		})
+	}
+
+	// 通知配置
+	smtpPort, _ := strconv.Atoi(getEnv("SMTP_PORT", "25"))
+	AppConfig.NotifyConfig = NotifyConfig{
+		EmailDriver:  getEnv("NOTIFY_EMAIL_DRIVER", "log"),
+		LogFile:      getEnv("NOTIFY_LOG_FILE", ""),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     smtpPort,
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@travel-guide.local"),
+		FrontendURL:  getEnv("FRONTEND_URL", "http://localhost:5173"),
 	}
 
 	return nil
@@ -87,4 +257,15 @@ func getEnv(key, defaultValue string) string {
 		return defaultValue
 	}
 	return value
-} 
\ No newline at end of file
+} 
+
+// splitList 解析逗号分隔的配置项，忽略空白项
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -33,6 +35,11 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to use database: %v", err)
 	}
 
+	// 早期版本把攻略表建成了 travel_guide，与模型使用的 travel_guides 不一致
+	if err := renameLegacyGuideTable(db); err != nil {
+		return nil, err
+	}
+
 	// 创建表（如果不存在）
 	err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS users (
@@ -41,8 +48,17 @@ func InitDB() (*gorm.DB, error) {
 			password VARCHAR(255) NOT NULL,
 			nickname VARCHAR(100) NOT NULL,
 			avatar_url VARCHAR(255),
-			role ENUM('admin', 'user') NOT NULL DEFAULT 'user',
+			email VARCHAR(255) UNIQUE,
+			email_verified_at TIMESTAMP NULL,
+			phone VARCHAR(32) UNIQUE,
+			phone_verified_at TIMESTAMP NULL,
+			totp_secret VARCHAR(64),
+			totp_enabled_at TIMESTAMP NULL,
+			totp_last_step BIGINT NOT NULL DEFAULT 0,
+			role VARCHAR(50) NOT NULL DEFAULT 'user',
 			status ENUM('active', 'banned') NOT NULL DEFAULT 'active',
+			ban_reason VARCHAR(255),
+			banned_until TIMESTAMP NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			deleted_at TIMESTAMP NULL,
@@ -69,12 +85,12 @@ func InitDB() (*gorm.DB, error) {
 	}
 
 	err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS travel_guide (
+		CREATE TABLE IF NOT EXISTS travel_guides (
 			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 			title VARCHAR(255) NOT NULL,
 			content TEXT NOT NULL,
-			images TEXT,
 			user_id BIGINT UNSIGNED NOT NULL,
+			status ENUM('draft', 'scheduled', 'published', 'archived') NOT NULL DEFAULT 'published',
 			published_at TIMESTAMP NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -82,11 +98,12 @@ func InitDB() (*gorm.DB, error) {
 			FOREIGN KEY (user_id) REFERENCES users(id),
 			FULLTEXT INDEX idx_ft_title_content (title, content),
 			INDEX idx_user_id (user_id),
-			INDEX idx_published_at (published_at)
+			INDEX idx_published_at (published_at),
+			INDEX idx_status_published_at (status, published_at)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 	`).Error
 	if err != nil {
-		return nil, fmt.Errorf("failed to create travel_guide table: %v", err)
+		return nil, fmt.Errorf("failed to create travel_guides table: %v", err)
 	}
 
 	err = db.Exec(`
@@ -95,7 +112,7 @@ func InitDB() (*gorm.DB, error) {
 			tag_id BIGINT UNSIGNED NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (guide_id, tag_id),
-			FOREIGN KEY (guide_id) REFERENCES travel_guide(id),
+			FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
 			FOREIGN KEY (tag_id) REFERENCES tags(id),
 			INDEX idx_tag_id (tag_id)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -119,6 +136,382 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to create user_tags table: %v", err)
 	}
 
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS guide_revisions (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			guide_id BIGINT UNSIGNED NOT NULL,
+			version INT NOT NULL,
+			title VARCHAR(255) NOT NULL,
+			content TEXT NOT NULL,
+			images TEXT,
+			tags TEXT,
+			editor_id BIGINT UNSIGNED NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+			FOREIGN KEY (editor_id) REFERENCES users(id),
+			UNIQUE INDEX idx_guide_version (guide_id, version)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create guide_revisions table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS guide_likes (
+			user_id BIGINT UNSIGNED NOT NULL,
+			guide_id BIGINT UNSIGNED NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, guide_id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+			INDEX idx_guide_id (guide_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create guide_likes table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS guide_favorites (
+			user_id BIGINT UNSIGNED NOT NULL,
+			guide_id BIGINT UNSIGNED NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, guide_id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+			INDEX idx_guide_id (guide_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create guide_favorites table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS comments (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			guide_id BIGINT UNSIGNED NOT NULL,
+			user_id BIGINT UNSIGNED NOT NULL,
+			parent_id BIGINT UNSIGNED NULL,
+			reply_to_user_id BIGINT UNSIGNED NULL,
+			content TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			deleted_at TIMESTAMP NULL,
+			FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (parent_id) REFERENCES comments(id),
+			INDEX idx_guide_parent (guide_id, parent_id),
+			INDEX idx_parent_id (parent_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comments table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_follows (
+			follower_id BIGINT UNSIGNED NOT NULL,
+			followee_id BIGINT UNSIGNED NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (follower_id, followee_id),
+			FOREIGN KEY (follower_id) REFERENCES users(id),
+			FOREIGN KEY (followee_id) REFERENCES users(id),
+			INDEX idx_followee_id (followee_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_follows table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_sessions (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			refresh_token_hash CHAR(64) NOT NULL UNIQUE,
+			previous_token_hash CHAR(64),
+			user_agent VARCHAR(255),
+			ip VARCHAR(64),
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			INDEX idx_user_id (user_id),
+			INDEX idx_previous_token_hash (previous_token_hash)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_sessions table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS roles (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(50) NOT NULL UNIQUE,
+			description VARCHAR(255),
+			is_system BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create roles table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS role_permissions (
+			role_id BIGINT UNSIGNED NOT NULL,
+			permission VARCHAR(100) NOT NULL,
+			PRIMARY KEY (role_id, permission),
+			FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role_permissions table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			provider VARCHAR(50) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			email VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			UNIQUE KEY idx_provider_subject (provider, subject),
+			INDEX idx_user_id (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_identities table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS verification_tokens (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			purpose ENUM('email_verify', 'phone_verify', 'password_reset') NOT NULL,
+			target VARCHAR(255) NOT NULL,
+			token_hash CHAR(64) NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			INDEX idx_token_hash (token_hash),
+			INDEX idx_user_purpose (user_id, purpose)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification_tokens table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_recovery_codes (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			code_hash CHAR(64) NOT NULL,
+			used_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			INDEX idx_user_id (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_recovery_codes table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS images (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			object_key VARCHAR(255) NOT NULL UNIQUE,
+			url VARCHAR(512) NOT NULL,
+			format VARCHAR(10) NOT NULL,
+			width INT NOT NULL,
+			height INT NOT NULL,
+			bytes BIGINT NOT NULL,
+			placeholder VARCHAR(7),
+			variants TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			INDEX idx_user_id (user_id),
+			INDEX idx_url (url)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create images table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS guide_images (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			guide_id BIGINT UNSIGNED NOT NULL,
+			image_id BIGINT UNSIGNED NULL,
+			position INT NOT NULL,
+			url VARCHAR(512) NOT NULL,
+			caption VARCHAR(500),
+			width INT NOT NULL DEFAULT 0,
+			height INT NOT NULL DEFAULT 0,
+			owner_id BIGINT UNSIGNED NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+			FOREIGN KEY (image_id) REFERENCES images(id),
+			FOREIGN KEY (owner_id) REFERENCES users(id),
+			INDEX idx_guide_position (guide_id, position),
+			INDEX idx_image_id (image_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create guide_images table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS pending_uploads (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			object_key VARCHAR(255) NOT NULL UNIQUE,
+			content_type VARCHAR(50) NOT NULL,
+			size BIGINT NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			INDEX idx_user_id (user_id),
+			INDEX idx_expires_at (expires_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pending_uploads table: %v", err)
+	}
+
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT UNSIGNED NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			key_hash CHAR(64) NOT NULL UNIQUE,
+			scopes VARCHAR(255) NOT NULL,
+			last_used_at TIMESTAMP NULL,
+			expires_at TIMESTAMP NULL,
+			revoked_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			INDEX idx_user_id (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api_keys table: %v", err)
+	}
+
+	// 为旧版本创建的表补充新增字段
+	err = addColumnIfMissing(db, "travel_guides", "status",
+		"ENUM('draft', 'scheduled', 'published', 'archived') NOT NULL DEFAULT 'published' AFTER user_id, "+
+			"ADD INDEX idx_status_published_at (status, published_at)")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "ban_reason", "VARCHAR(255) AFTER status")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "banned_until", "TIMESTAMP NULL AFTER ban_reason")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "email", "VARCHAR(255) UNIQUE AFTER avatar_url")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "email_verified_at", "TIMESTAMP NULL AFTER email")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "phone", "VARCHAR(32) UNIQUE AFTER email_verified_at")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "phone_verified_at", "TIMESTAMP NULL AFTER phone")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "totp_secret", "VARCHAR(64) AFTER phone_verified_at")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "totp_enabled_at", "TIMESTAMP NULL AFTER totp_secret")
+	if err != nil {
+		return nil, err
+	}
+
+	err = addColumnIfMissing(db, "users", "totp_last_step", "BIGINT NOT NULL DEFAULT 0 AFTER totp_enabled_at")
+	if err != nil {
+		return nil, err
+	}
+
+	// 角色改为可配置后 users.role 不再使用 ENUM
+	var roleType string
+	db.Raw(`SELECT DATA_TYPE FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = 'role'`).Scan(&roleType)
+	if roleType == "enum" {
+		if err := db.Exec("ALTER TABLE users MODIFY COLUMN role VARCHAR(50) NOT NULL DEFAULT 'user'").Error; err != nil {
+			return nil, fmt.Errorf("failed to migrate users.role: %v", err)
+		}
+	}
+
+	if err := migrateGuideImages(db); err != nil {
+		return nil, err
+	}
+
+	if err := backfillGuideRevisions(db); err != nil {
+		return nil, err
+	}
+
+	// 内置角色及其默认权限，已存在的角色不会被覆盖
+	err = db.Exec(`
+		INSERT IGNORE INTO roles (name, description, is_system) VALUES
+		('admin', '管理员，拥有全部权限', TRUE),
+		('moderator', '版主，负责内容审核和用户管理', FALSE),
+		('editor', '编辑，负责攻略和标签整理', FALSE),
+		('user', '普通用户', TRUE);
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert initial roles: %v", err)
+	}
+
+	err = db.Exec(`
+		INSERT IGNORE INTO role_permissions (role_id, permission)
+		SELECT r.id, p.permission
+		FROM roles r
+		JOIN (
+			SELECT 'admin' AS role, '*' AS permission UNION ALL
+			SELECT 'moderator', 'guide.view.hidden' UNION ALL
+			SELECT 'moderator', 'guide.delete.any' UNION ALL
+			SELECT 'moderator', 'guide.restore' UNION ALL
+			SELECT 'moderator', 'comment.delete.any' UNION ALL
+			SELECT 'moderator', 'user.view' UNION ALL
+			SELECT 'moderator', 'user.ban' UNION ALL
+			SELECT 'moderator', 'user.session.revoke' UNION ALL
+			SELECT 'editor', 'guide.view.hidden' UNION ALL
+			SELECT 'editor', 'guide.edit.any' UNION ALL
+			SELECT 'editor', 'tag.delete' UNION ALL
+			SELECT 'editor', 'tag.merge'
+		) p ON p.role = r.name
+		WHERE NOT EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.role_id = r.id);
+	`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert initial role permissions: %v", err)
+	}
+
 	// 插入初始数据（如果不存在）
 	err = db.Exec(`
 		INSERT IGNORE INTO tags (name) VALUES 
@@ -135,34 +528,172 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to insert initial tags: %v", err)
 	}
 
-	err = db.Exec(`
-		INSERT IGNORE INTO users (username, password, nickname, avatar_url, role) VALUES 
-		('admin', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', 'admin', 'https://example.com/avatar1.jpg', 'admin'),
-		('travel_lover', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '旅行爱好者', 'https://example.com/avatar1.jpg', 'user'),
-		('food_explorer', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '美食探索家', 'https://example.com/avatar2.jpg', 'user'),
-		('nature_seeker', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '自然追寻者', 'https://example.com/avatar3.jpg', 'user'),
-		('city_wanderer', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '城市漫游者', 'https://example.com/avatar4.jpg', 'user'),
-		('adventure_seeker', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '冒险家', 'https://example.com/avatar5.jpg', 'user');
-	`).Error
+	return db, nil
+}
+
+// addColumnIfMissing 当表中不存在该字段时执行 ALTER TABLE ADD COLUMN
+func addColumnIfMissing(db *gorm.DB, table, column, definition string) error {
+	if db.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)).Error; err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %v", table, column, err)
+	}
+	return nil
+}
+
+// renameLegacyGuideTable 把旧版本创建的 travel_guide 表改名为 travel_guides，
+// 其他表指向它的外键由 InnoDB 随表名一起更新
+func renameLegacyGuideTable(db *gorm.DB) error {
+	if !db.Migrator().HasTable("travel_guide") || db.Migrator().HasTable("travel_guides") {
+		return nil
+	}
+	if err := db.Exec("RENAME TABLE travel_guide TO travel_guides").Error; err != nil {
+		return fmt.Errorf("failed to rename travel_guide to travel_guides: %v", err)
+	}
+	return nil
+}
+
+// legacyGuideImage 旧版本 travel_guides.images 中的一张图片，
+// 早期只保存 URL 字符串，之后改为带尺寸的对象
+type legacyGuideImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+func parseLegacyGuideImages(raw string) []legacyGuideImage {
+	var items []json.RawMessage
+	_ = json.Unmarshal([]byte(raw), &items)
+
+	images := make([]legacyGuideImage, 0, len(items))
+	for _, item := range items {
+		var image legacyGuideImage
+		if err := json.Unmarshal(item, &image.URL); err != nil {
+			if err := json.Unmarshal(item, &image); err != nil {
+				continue
+			}
+		}
+		if image.URL != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// migrateGuideImages 把旧版本保存在 travel_guides.images 中的图片迁移到 guide_images，
+// 完成后删除该字段。已有 guide_images 记录的攻略会被跳过，中途失败可以重新执行
+func migrateGuideImages(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("travel_guides", "images") {
+		return nil
+	}
+
+	var guides []struct {
+		ID     uint
+		UserID uint
+		Images string
+	}
+	err := db.Raw(`SELECT id, user_id, images FROM travel_guides
+		WHERE images IS NOT NULL AND images NOT IN ('', '[]')
+		AND NOT EXISTS (SELECT 1 FROM guide_images gi WHERE gi.guide_id = travel_guides.id)`).Scan(&guides).Error
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert initial users: %v", err)
+		return fmt.Errorf("failed to load legacy guide images: %v", err)
 	}
 
-	err = db.Exec(`
-		INSERT IGNORE INTO user_tags (user_id, tag_id) 
-		SELECT u.id, t.id 
-		FROM users u 
-		CROSS JOIN tags t 
-		WHERE 
-			(u.username = 'travel_lover' AND t.name IN ('自然风光', '城市探索', '海岛度假')) OR
-			(u.username = 'food_explorer' AND t.name IN ('城市探索', '乡村田园')) OR
-			(u.username = 'nature_seeker' AND t.name IN ('自然风光', '高原雪山', '森林徒步', '草原牧场')) OR
-			(u.username = 'city_wanderer' AND t.name IN ('城市探索', '海岛度假')) OR
-			(u.username = 'adventure_seeker' AND t.name IN ('高原雪山', '沙漠戈壁', '森林徒步'));
-	`).Error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, guide := range guides {
+			for position, legacy := range parseLegacyGuideImages(guide.Images) {
+				// 通过本站上传的图片关联到 images 表，上传者作为图片所有者
+				var image struct {
+					ID     uint
+					UserID uint
+					Width  int
+					Height int
+				}
+				if err := tx.Raw("SELECT id, user_id, width, height FROM images WHERE url = ? LIMIT 1", legacy.URL).Scan(&image).Error; err != nil {
+					return err
+				}
+
+				var imageID *uint
+				ownerID, width, height := guide.UserID, legacy.Width, legacy.Height
+				if image.ID != 0 {
+					imageID = &image.ID
+					ownerID, width, height = image.UserID, image.Width, image.Height
+				}
+				if err := tx.Exec(`INSERT INTO guide_images (guide_id, image_id, position, url, width, height, owner_id)
+					VALUES (?, ?, ?, ?, ?, ?, ?)`, guide.ID, imageID, position, legacy.URL, width, height, ownerID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert initial user_tags: %v", err)
+		return fmt.Errorf("failed to migrate guide images: %v", err)
 	}
 
-	return db, nil
-} 
\ No newline at end of file
+	if err := db.Exec("ALTER TABLE travel_guides DROP COLUMN images").Error; err != nil {
+		return fmt.Errorf("failed to drop travel_guides.images: %v", err)
+	}
+	return nil
+}
+
+// revisionImage 版本快照 images 字段中的一张图片，读取时缺少的尺寸用原图补齐
+type revisionImage struct {
+	ID      uint   `json:"id,omitempty"`
+	ImageID uint   `json:"image_id,omitempty"`
+	URL     string `json:"url"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// backfillGuideRevisions 为引入版本记录之前创建的攻略补一条作者保存的初始版本，
+// 否则第一次编辑后原内容无法回滚。只处理还没有任何版本的攻略，可以重复执行
+func backfillGuideRevisions(db *gorm.DB) error {
+	var guides []struct {
+		ID        uint
+		UserID    uint
+		Title     string
+		Content   string
+		UpdatedAt time.Time
+	}
+	err := db.Raw(`SELECT id, user_id, title, content, updated_at FROM travel_guides
+		WHERE NOT EXISTS (SELECT 1 FROM guide_revisions gr WHERE gr.guide_id = travel_guides.id)`).Scan(&guides).Error
+	if err != nil {
+		return fmt.Errorf("failed to load guides without revisions: %v", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, guide := range guides {
+			images := []revisionImage{}
+			if err := tx.Raw(`SELECT id, image_id, url, width, height, caption FROM guide_images
+				WHERE guide_id = ? ORDER BY position, id`, guide.ID).Scan(&images).Error; err != nil {
+				return err
+			}
+			tags := []string{}
+			if err := tx.Raw(`SELECT t.name FROM tags t JOIN guide_tags gt ON gt.tag_id = t.id
+				WHERE gt.guide_id = ? AND t.deleted_at IS NULL ORDER BY t.id`, guide.ID).Scan(&tags).Error; err != nil {
+				return err
+			}
+
+			imagesJSON, err := json.Marshal(images)
+			if err != nil {
+				return err
+			}
+			tagsJSON, err := json.Marshal(tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec(`INSERT INTO guide_revisions (guide_id, version, title, content, images, tags, editor_id, created_at)
+				VALUES (?, 1, ?, ?, ?, ?, ?, ?)`, guide.ID, guide.Title, guide.Content, string(imagesJSON), string(tagsJSON), guide.UserID, guide.UpdatedAt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to backfill guide revisions: %v", err)
+	}
+	return nil
+}
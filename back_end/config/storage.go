@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+
+	"travel_guide/utils/storage"
+)
+
+// NewStorage 按 STORAGE_DRIVER 创建文件存储，初始化失败或驱动未知时返回错误，不会回退到本地目录
+func NewStorage() (storage.Storage, error) {
+	cfg := AppConfig.StorageConfig
+
+	// 构造函数失败时返回的是类型化的 nil 指针，不能直接作为接口返回
+	var store storage.Storage
+	var err error
+	switch cfg.Driver {
+	case "local":
+		// 未配置访问地址时使用本机服务地址
+		publicURL := cfg.PublicURL
+		if publicURL == "" {
+			publicURL = fmt.Sprintf("http://localhost:%d%s", AppConfig.ServerConfig.Port, cfg.LocalURLPrefix)
+		}
+		store, err = storage.NewLocalStorage(cfg.LocalDir, cfg.LocalURLPrefix, publicURL)
+	case "oss":
+		store, err = storage.NewOSSStorage(storage.OSSConfig{
+			Endpoint:        AppConfig.OSSConfig.Endpoint,
+			AccessKeyID:     AppConfig.OSSConfig.AccessKeyID,
+			AccessKeySecret: AppConfig.OSSConfig.AccessKeySecret,
+			Bucket:          AppConfig.OSSConfig.BucketName,
+			PublicURL:       cfg.PublicURL,
+		})
+	case "s3":
+		store, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretKey,
+			PathStyle:       cfg.S3PathStyle,
+			PublicURL:       cfg.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init %s storage: %v", cfg.Driver, err)
+	}
+	return store, nil
+}
@@ -0,0 +1,610 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"travel_guide/config"
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+	"travel_guide/utils/loginguard"
+	"travel_guide/utils/notify"
+	"travel_guide/utils/validate"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 各类令牌的有效期
+const (
+	emailVerifyTTL   = 24 * time.Hour
+	phoneVerifyTTL   = 10 * time.Minute
+	passwordResetTTL = 30 * time.Minute
+	// 同一用户同一用途两次发送的最小间隔
+	tokenResendInterval = time.Minute
+	// 验证码允许输错的次数，超过后作废
+	maxCodeAttempts = 5
+)
+
+var (
+	errTokenTooFrequent = errors.New("发送过于频繁，请稍后再试")
+	errTokenInvalid     = errors.New("验证码无效或已过期")
+)
+
+// AccountController 联系方式验证和找回密码
+type AccountController struct {
+	db       *gorm.DB
+	notifier notify.Notifier
+	// 按手机号统计验证码输错次数，防止穷举
+	codeGuard *loginguard.Guard
+}
+
+func NewAccountController(db *gorm.DB) *AccountController {
+	return &AccountController{
+		db:       db,
+		notifier: newNotifier(),
+		codeGuard: loginguard.NewGuard(loginguard.NewMemoryStore(time.Hour), loginguard.Policy{
+			FreeAttempts:     maxCodeAttempts,
+			BaseDelay:        time.Minute,
+			MaxDelay:         10 * time.Minute,
+			LockoutThreshold: maxCodeAttempts * 2,
+			LockoutDuration:  time.Hour,
+			ResetAfter:       time.Hour,
+		}, "code:"),
+	}
+}
+
+// newNotifier 按配置创建通知发送器，短信没有接入服务商，始终写入日志
+func newNotifier() notify.Notifier {
+	cfg := config.AppConfig.NotifyConfig
+	logNotifier, err := notify.NewFileNotifier(cfg.LogFile)
+	if err != nil {
+		logger.ErrorLogger.Printf("打开通知日志文件失败，改为输出到标准输出: %v", err)
+		logNotifier = notify.NewLogNotifier(os.Stdout)
+	}
+
+	router := &notify.Router{Email: logNotifier, SMS: logNotifier}
+	if cfg.EmailDriver == "smtp" {
+		router.Email = notify.NewSMTPNotifier(notify.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	}
+	return router
+}
+
+// UpdateContactRequest 修改联系方式，传空字符串表示清除，未传的字段保持不变
+type UpdateContactRequest struct {
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ForgotPasswordRequest 通过已验证的邮箱或手机号找回密码，二选一
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// ResetPasswordRequest 邮箱找回时 token 为链接中的令牌；手机找回时 token 为短信验证码，并需要传 phone
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	Phone       string `json:"phone"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// newLinkToken 生成邮件链接中使用的随机令牌
+func newLinkToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// newNumericCode 生成 6 位数字短信验证码
+func newNumericCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// issueToken 为用户生成一次性令牌，同一用途之前未使用的令牌随即作废
+func (ac *AccountController) issueToken(userID uint, purpose models.TokenPurpose, target, secret string, ttl time.Duration) error {
+	return ac.db.Transaction(func(tx *gorm.DB) error {
+		var recent int64
+		tx.Model(&models.VerificationToken{}).
+			Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, time.Now().Add(-tokenResendInterval)).
+			Count(&recent)
+		if recent > 0 {
+			return errTokenTooFrequent
+		}
+
+		if err := tx.Model(&models.VerificationToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.VerificationToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			Target:    target,
+			TokenHash: middleware.HashToken(secret),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+}
+
+// consumeToken 校验并使用令牌，userID 为 0 时不限定用户
+func consumeToken(tx *gorm.DB, purpose models.TokenPurpose, secret string, userID uint) (*models.VerificationToken, error) {
+	query := tx.Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		purpose, middleware.HashToken(secret), time.Now())
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var token models.VerificationToken
+	if err := query.First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errTokenInvalid
+		}
+		return nil, err
+	}
+
+	// 条件更新保证并发请求中只有一个能使用成功
+	result := tx.Model(&models.VerificationToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errTokenInvalid
+	}
+	return &token, nil
+}
+
+func (ac *AccountController) send(msg notify.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return ac.notifier.Send(ctx, msg)
+}
+
+// sendEmailVerification 生成邮箱验证令牌并发送验证链接
+func (ac *AccountController) sendEmailVerification(user *models.User) error {
+	token, err := newLinkToken()
+	if err != nil {
+		return err
+	}
+	if err := ac.issueToken(user.ID, models.TokenPurposeEmailVerify, *user.Email, token, emailVerifyTTL); err != nil {
+		return err
+	}
+
+	link := strings.TrimSuffix(config.AppConfig.NotifyConfig.FrontendURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	return ac.send(notify.Message{
+		Channel: notify.ChannelEmail,
+		To:      *user.Email,
+		Subject: "验证你的邮箱",
+		Body:    fmt.Sprintf("%s，你好：\n\n请在24小时内打开以下链接完成邮箱验证：\n%s\n\n如果这不是你本人的操作，请忽略本邮件。", user.Nickname, link),
+	})
+}
+
+// sendPhoneVerification 生成短信验证码并发送
+func (ac *AccountController) sendPhoneVerification(user *models.User) error {
+	code, err := newNumericCode()
+	if err != nil {
+		return err
+	}
+	if err := ac.issueToken(user.ID, models.TokenPurposePhoneVerify, *user.Phone, code, phoneVerifyTTL); err != nil {
+		return err
+	}
+
+	return ac.send(notify.Message{
+		Channel: notify.ChannelSMS,
+		To:      *user.Phone,
+		Body:    fmt.Sprintf("您的验证码为 %s，10分钟内有效。", code),
+	})
+}
+
+// writeSendError 将发送失败转换为响应
+func writeSendError(c *gin.Context, err error) {
+	if errors.Is(err, errTokenTooFrequent) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	logger.ErrorLogger.Printf("发送验证消息失败: %v", err)
+	c.JSON(http.StatusOK, types.ErrorResponse(1, "发送失败，请稍后再试"))
+}
+
+// UpdateContact 修改邮箱或手机号，修改后需要重新验证
+func (ac *AccountController) UpdateContact(c *gin.Context) {
+	var req UpdateContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	user, ok := currentActiveUser(ac.db, c)
+	if !ok {
+		return
+	}
+
+	var errs validate.Errors
+	updates := map[string]interface{}{}
+	if req.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*req.Email))
+		if email == "" {
+			updates["email"] = nil
+			updates["email_verified_at"] = nil
+		} else if msg := validate.Email(email); msg != "" {
+			errs.Add("email", msg)
+		} else if user.Email == nil || *user.Email != email {
+			if ac.contactTaken("email", email, user.ID) {
+				errs.Add("email", "该邮箱已被使用")
+			}
+			updates["email"] = email
+			updates["email_verified_at"] = nil
+		}
+	}
+	if req.Phone != nil {
+		phone := strings.TrimSpace(*req.Phone)
+		if phone == "" {
+			updates["phone"] = nil
+			updates["phone_verified_at"] = nil
+		} else if msg := validate.Phone(phone); msg != "" {
+			errs.Add("phone", msg)
+		} else if user.Phone == nil || *user.Phone != phone {
+			if ac.contactTaken("phone", phone, user.ID) {
+				errs.Add("phone", "该手机号已被使用")
+			}
+			updates["phone"] = phone
+			updates["phone_verified_at"] = nil
+		}
+	}
+	if errs.Has() {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(errs))
+		return
+	}
+
+	if len(updates) > 0 {
+		if err := ac.db.Model(user).Updates(updates).Error; err != nil {
+			logger.ErrorLogger.Printf("用户 %d 修改联系方式失败: %v", user.ID, err)
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "修改联系方式失败"))
+			return
+		}
+		ac.db.First(user, user.ID)
+	}
+
+	// 新的联系方式自动发送一次验证，失败时用户可以手动重发
+	if _, ok := updates["email"]; ok && user.Email != nil {
+		if err := ac.sendEmailVerification(user); err != nil {
+			logger.ErrorLogger.Printf("用户 %d 发送邮箱验证失败: %v", user.ID, err)
+		}
+	}
+	if _, ok := updates["phone"]; ok && user.Phone != nil {
+		if err := ac.sendPhoneVerification(user); err != nil {
+			logger.ErrorLogger.Printf("用户 %d 发送手机验证码失败: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(toCurrentUserResponse(*user), "修改联系方式成功"))
+}
+
+// contactTaken 判断邮箱或手机号是否已被其他用户（包括已注销用户）占用
+func (ac *AccountController) contactTaken(column, value string, exceptUserID uint) bool {
+	var count int64
+	ac.db.Unscoped().Model(&models.User{}).
+		Where(column+" = ? AND id <> ?", value, exceptUserID).
+		Count(&count)
+	return count > 0
+}
+
+// SendEmailVerification 重新发送邮箱验证链接
+func (ac *AccountController) SendEmailVerification(c *gin.Context) {
+	user, ok := currentActiveUser(ac.db, c)
+	if !ok {
+		return
+	}
+	if user.Email == nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "尚未设置邮箱"))
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "邮箱已验证"))
+		return
+	}
+
+	if err := ac.sendEmailVerification(user); err != nil {
+		writeSendError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "验证邮件已发送"))
+}
+
+// VerifyEmail 通过邮件链接中的令牌验证邮箱，无需登录
+func (ac *AccountController) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	err := ac.db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeToken(tx, models.TokenPurposeEmailVerify, req.Token, 0)
+		if err != nil {
+			return err
+		}
+		// 令牌只对发送时的邮箱有效，期间修改过邮箱则失效
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", token.UserID, token.Target).
+			Update("email_verified_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTokenInvalid
+		}
+		return nil
+	})
+	if errors.Is(err, errTokenInvalid) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证链接无效或已过期"))
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Printf("验证邮箱失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证邮箱失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "邮箱验证成功"))
+}
+
+// SendPhoneVerification 重新发送手机验证码
+func (ac *AccountController) SendPhoneVerification(c *gin.Context) {
+	user, ok := currentActiveUser(ac.db, c)
+	if !ok {
+		return
+	}
+	if user.Phone == nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "尚未设置手机号"))
+		return
+	}
+	if user.PhoneVerifiedAt != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "手机号已验证"))
+		return
+	}
+
+	if err := ac.sendPhoneVerification(user); err != nil {
+		writeSendError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "验证码已发送"))
+}
+
+// VerifyPhone 使用短信验证码验证手机号
+func (ac *AccountController) VerifyPhone(c *gin.Context) {
+	var req VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	user, ok := currentActiveUser(ac.db, c)
+	if !ok {
+		return
+	}
+	if user.Phone == nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "尚未设置手机号"))
+		return
+	}
+
+	if !ac.checkCode(c, *user.Phone) {
+		return
+	}
+
+	err := ac.db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeToken(tx, models.TokenPurposePhoneVerify, req.Code, user.ID)
+		if err != nil {
+			return err
+		}
+		if token.Target != *user.Phone {
+			return errTokenInvalid
+		}
+		return tx.Model(user).Update("phone_verified_at", time.Now()).Error
+	})
+	if errors.Is(err, errTokenInvalid) {
+		ac.codeGuard.Fail(*user.Phone)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Printf("用户 %d 验证手机号失败: %v", user.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证手机号失败"))
+		return
+	}
+	ac.codeGuard.Reset(*user.Phone)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "手机号验证成功"))
+}
+
+// checkCode 检查手机号的验证码是否因输错次数过多被暂时禁止，被禁止时已写入错误响应
+func (ac *AccountController) checkCode(c *gin.Context, phone string) bool {
+	if wait, blocked := ac.codeGuard.Check(phone); blocked {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, fmt.Sprintf("验证码错误次数过多，请在%d秒后重试", int(wait.Seconds())+1)))
+		return false
+	}
+	return true
+}
+
+// ForgotPassword 向已验证的邮箱或手机号发送重置密码的链接或验证码
+// 无论账号是否存在都返回相同的结果，避免泄露用户的联系方式
+func (ac *AccountController) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	phone := strings.TrimSpace(req.Phone)
+	if (email == "") == (phone == "") {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请填写邮箱或手机号其中一项"))
+		return
+	}
+
+	var user models.User
+	var err error
+	if email != "" {
+		err = ac.db.Where("email = ? AND email_verified_at IS NOT NULL", email).First(&user).Error
+	} else {
+		err = ac.db.Where("phone = ? AND phone_verified_at IS NOT NULL", phone).First(&user).Error
+	}
+
+	if err == nil {
+		if email != "" {
+			err = ac.sendPasswordResetEmail(&user)
+		} else {
+			err = ac.sendPasswordResetCode(&user)
+		}
+		if err != nil && !errors.Is(err, errTokenTooFrequent) {
+			logger.ErrorLogger.Printf("用户 %d 发送重置密码消息失败: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "如果该联系方式已绑定并验证，你将收到重置密码的消息"))
+}
+
+func (ac *AccountController) sendPasswordResetEmail(user *models.User) error {
+	token, err := newLinkToken()
+	if err != nil {
+		return err
+	}
+	if err := ac.issueToken(user.ID, models.TokenPurposePasswordReset, *user.Email, token, passwordResetTTL); err != nil {
+		return err
+	}
+
+	link := strings.TrimSuffix(config.AppConfig.NotifyConfig.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	return ac.send(notify.Message{
+		Channel: notify.ChannelEmail,
+		To:      *user.Email,
+		Subject: "重置密码",
+		Body:    fmt.Sprintf("%s，你好：\n\n请在30分钟内打开以下链接重置密码：\n%s\n\n如果这不是你本人的操作，请忽略本邮件，你的密码不会被修改。", user.Nickname, link),
+	})
+}
+
+func (ac *AccountController) sendPasswordResetCode(user *models.User) error {
+	code, err := newNumericCode()
+	if err != nil {
+		return err
+	}
+	if err := ac.issueToken(user.ID, models.TokenPurposePasswordReset, *user.Phone, code, phoneVerifyTTL); err != nil {
+		return err
+	}
+
+	return ac.send(notify.Message{
+		Channel: notify.ChannelSMS,
+		To:      *user.Phone,
+		Body:    fmt.Sprintf("您正在重置密码，验证码为 %s，10分钟内有效。如非本人操作请忽略。", code),
+	})
+}
+
+// ResetPassword 使用令牌或验证码重置密码，成功后所有登录会话失效
+func (ac *AccountController) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	if msg := validate.Password(req.NewPassword, config.AppConfig.UserConfig.PasswordPolicy()); msg != "" {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(validate.Errors{{Field: "new_password", Message: msg}}))
+		return
+	}
+
+	// 短信验证码只有 6 位，需要限定手机号并限制尝试次数
+	phone := strings.TrimSpace(req.Phone)
+	var userID uint
+	if phone != "" {
+		if !ac.checkCode(c, phone) {
+			return
+		}
+		var user models.User
+		if err := ac.db.Where("phone = ?", phone).First(&user).Error; err != nil {
+			ac.codeGuard.Fail(phone)
+			c.JSON(http.StatusOK, types.ErrorResponse(1, errTokenInvalid.Error()))
+			return
+		}
+		userID = user.ID
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "密码加密失败"))
+		return
+	}
+
+	var user models.User
+	err = ac.db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeToken(tx, models.TokenPurposePasswordReset, req.Token, userID)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return errTokenInvalid
+		}
+		// 令牌只对发送时的联系方式有效；未传手机号时只接受邮件中的长令牌，短信验证码必须配合手机号使用
+		if phone == "" && (user.Email == nil || *user.Email != token.Target) {
+			return errTokenInvalid
+		}
+		if phone != "" && (user.Phone == nil || *user.Phone != token.Target) {
+			return errTokenInvalid
+		}
+		if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		return middleware.RevokeUserSessions(tx, user.ID, 0)
+	})
+	if errors.Is(err, errTokenInvalid) {
+		if phone != "" {
+			ac.codeGuard.Fail(phone)
+		}
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Printf("重置密码失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "重置密码失败"))
+		return
+	}
+	if phone != "" {
+		ac.codeGuard.Reset(phone)
+	}
+
+	logger.InfoLogger.Printf("用户 %d 通过找回密码重置了密码", user.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "密码已重置，请重新登录"))
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/utils/loginguard"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAccountController(t *testing.T) *AccountController {
+	t.Helper()
+	return &AccountController{
+		db: newTestDB(t),
+		codeGuard: loginguard.NewGuard(loginguard.NewMemoryStore(time.Hour), loginguard.Policy{
+			FreeAttempts:     maxCodeAttempts,
+			BaseDelay:        time.Minute,
+			MaxDelay:         10 * time.Minute,
+			LockoutThreshold: maxCodeAttempts * 2,
+			LockoutDuration:  time.Hour,
+			ResetAfter:       time.Hour,
+		}, "code:"),
+	}
+}
+
+// createContactUser 创建邮箱和手机号都已验证的用户
+func createContactUser(t *testing.T, ac *AccountController) models.User {
+	t.Helper()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("OldPassw0rd!"), bcrypt.MinCost)
+	email, phone := "alice@example.com", "13800000000"
+	now := time.Now()
+	user := models.User{
+		Username:        "alice",
+		Password:        string(hash),
+		Nickname:        "alice",
+		Email:           &email,
+		EmailVerifiedAt: &now,
+		Phone:           &phone,
+		PhoneVerifiedAt: &now,
+		Role:            models.RoleUser,
+		Status:          models.StatusActive,
+	}
+	if err := ac.db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// insertToken 直接写入令牌，绕过发送频率限制
+func insertToken(t *testing.T, ac *AccountController, userID uint, purpose models.TokenPurpose, target, secret string, expiresAt time.Time) {
+	t.Helper()
+	if err := ac.db.Create(&models.VerificationToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Target:    target,
+		TokenHash: middleware.HashToken(secret),
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		t.Fatalf("insert token: %v", err)
+	}
+}
+
+func TestConsumeTokenSingleUse(t *testing.T) {
+	ac := newTestAccountController(t)
+	user := createContactUser(t, ac)
+	if err := ac.issueToken(user.ID, models.TokenPurposePasswordReset, *user.Email, "secret", passwordResetTTL); err != nil {
+		t.Fatalf("issueToken: %v", err)
+	}
+
+	token, err := consumeToken(ac.db, models.TokenPurposePasswordReset, "secret", user.ID)
+	if err != nil {
+		t.Fatalf("consumeToken: %v", err)
+	}
+	if token.UserID != user.ID || token.Target != *user.Email {
+		t.Fatalf("token = %+v", token)
+	}
+	if _, err := consumeToken(ac.db, models.TokenPurposePasswordReset, "secret", user.ID); !errors.Is(err, errTokenInvalid) {
+		t.Fatalf("second consumeToken: %v, want errTokenInvalid", err)
+	}
+}
+
+func TestConsumeTokenRejects(t *testing.T) {
+	ac := newTestAccountController(t)
+	user := createContactUser(t, ac)
+	insertToken(t, ac, user.ID, models.TokenPurposePasswordReset, *user.Email, "expired", time.Now().Add(-time.Second))
+	insertToken(t, ac, user.ID, models.TokenPurposeEmailVerify, *user.Email, "verify", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		purpose models.TokenPurpose
+		secret  string
+		userID  uint
+	}{
+		{"expired", models.TokenPurposePasswordReset, "expired", 0},
+		{"wrong purpose", models.TokenPurposePasswordReset, "verify", 0},
+		{"wrong user", models.TokenPurposeEmailVerify, "verify", user.ID + 1},
+		{"unknown", models.TokenPurposePasswordReset, "unknown", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := consumeToken(ac.db, tt.purpose, tt.secret, tt.userID); !errors.Is(err, errTokenInvalid) {
+				t.Fatalf("consumeToken: %v, want errTokenInvalid", err)
+			}
+		})
+	}
+}
+
+func TestIssueTokenInvalidatesPrevious(t *testing.T) {
+	ac := newTestAccountController(t)
+	user := createContactUser(t, ac)
+	insertToken(t, ac, user.ID, models.TokenPurposePasswordReset, *user.Email, "old", time.Now().Add(time.Hour))
+	// 把旧令牌的创建时间移出发送间隔
+	ac.db.Model(&models.VerificationToken{}).Where("1 = 1").Update("created_at", time.Now().Add(-2*tokenResendInterval))
+
+	if err := ac.issueToken(user.ID, models.TokenPurposePasswordReset, *user.Email, "new", passwordResetTTL); err != nil {
+		t.Fatalf("issueToken: %v", err)
+	}
+	if err := ac.issueToken(user.ID, models.TokenPurposePasswordReset, *user.Email, "newer", passwordResetTTL); !errors.Is(err, errTokenTooFrequent) {
+		t.Fatalf("resend within interval: %v, want errTokenTooFrequent", err)
+	}
+	if _, err := consumeToken(ac.db, models.TokenPurposePasswordReset, "old", 0); !errors.Is(err, errTokenInvalid) {
+		t.Fatalf("old token: %v, want errTokenInvalid", err)
+	}
+	if _, err := consumeToken(ac.db, models.TokenPurposePasswordReset, "new", 0); err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+}
+
+func resetPassword(t *testing.T, ac *AccountController, req ResetPasswordRequest) (int, string) {
+	t.Helper()
+	resp := performJSON(t, http.MethodPost, "/api/password/reset", "/api/password/reset", req, 0, ac.ResetPassword)
+	return resp.Code, resp.Message
+}
+
+func TestResetPasswordWithEmailToken(t *testing.T) {
+	ac := newTestAccountController(t)
+	user := createContactUser(t, ac)
+	insertToken(t, ac, user.ID, models.TokenPurposePasswordReset, *user.Email, "reset-token", time.Now().Add(passwordResetTTL))
+	ac.db.Create(&models.UserSession{UserID: user.ID, RefreshTokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)})
+
+	if code, msg := resetPassword(t, ac, ResetPasswordRequest{Token: "reset-token", NewPassword: "NewPassw0rd!"}); code != 0 {
+		t.Fatalf("reset: %s", msg)
+	}
+
+	var updated models.User
+	ac.db.First(&updated, user.ID)
+	if bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("NewPassw0rd!")) != nil {
+		t.Fatal("password was not changed")
+	}
+	var active int64
+	ac.db.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+	if active != 0 {
+		t.Fatalf("active sessions = %d, want 0", active)
+	}
+
+	// 令牌只能使用一次
+	if code, msg := resetPassword(t, ac, ResetPasswordRequest{Token: "reset-token", NewPassword: "Another1!"}); code == 0 || msg != errTokenInvalid.Error() {
+		t.Fatalf("reusing token: code %d, message %q", code, msg)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	ac := newTestAccountController(t)
+	user := createContactUser(t, ac)
+	insertToken(t, ac, user.ID, models.TokenPurposePasswordReset, *user.Email, "reset-token", time.Now().Add(-time.Second))
+
+	if code, msg := resetPassword(t, ac, ResetPasswordRequest{Token: "reset-token", NewPassword: "NewPassw0rd!"}); code == 0 || msg != errTokenInvalid.Error() {
+		t.Fatalf("expired token: code %d, message %q", code, msg)
+	}
+	var unchanged models.User
+	ac.db.First(&unchanged, user.ID)
+	if unchanged.Password != user.Password {
+		t.Fatal("password changed with an expired token")
+	}
+}
+
+func TestResetPasswordSMSCodeRequiresPhone(t *testing.T) {
+	ac := newTestAccountController(t)
+	user := createContactUser(t, ac)
+	insertToken(t, ac, user.ID, models.TokenPurposePasswordReset, *user.Phone, "123456", time.Now().Add(phoneVerifyTTL))
+
+	// 没有手机号时短信验证码不能当作邮件令牌使用
+	if code, _ := resetPassword(t, ac, ResetPasswordRequest{Token: "123456", NewPassword: "NewPassw0rd!"}); code == 0 {
+		t.Fatal("SMS code was accepted without a phone number")
+	}
+
+	insertToken(t, ac, user.ID, models.TokenPurposePasswordReset, *user.Phone, "654321", time.Now().Add(phoneVerifyTTL))
+	if code, msg := resetPassword(t, ac, ResetPasswordRequest{Token: "654321", Phone: *user.Phone, NewPassword: "NewPassw0rd!"}); code != 0 {
+		t.Fatalf("reset with phone: %s", msg)
+	}
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 每个用户最多同时持有的有效密钥数量
+const maxActiveAPIKeys = 20
+
+type APIKeyController struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyController(db *gorm.DB) *APIKeyController {
+	return &APIKeyController{db: db}
+}
+
+// CreateAPIKeyRequest 创建密钥，ExpiresInDays 为 0 表示永不过期
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+func toAPIKeyResponse(key models.APIKey) types.APIKeyResponse {
+	response := types.APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    strings.Fields(key.Scopes),
+		CreatedAt: key.CreatedAt.Unix(),
+	}
+	if key.LastUsedAt != nil {
+		response.LastUsedAt = key.LastUsedAt.Unix()
+	}
+	if key.ExpiresAt != nil {
+		response.ExpiresAt = key.ExpiresAt.Unix()
+	}
+	return response
+}
+
+// GetAPIKeyScopes 获取可授予 API 密钥的授权范围
+func (kc *APIKeyController) GetAPIKeyScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, types.SuccessResponse(models.APIKeyScopes, "获取授权范围成功"))
+}
+
+// GetMyAPIKeys 获取当前用户未撤销的密钥，不返回密钥明文
+func (kc *APIKeyController) GetMyAPIKeys(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var keys []models.APIKey
+	if err := kc.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		logger.ErrorLogger.Printf("获取用户 %v 的API密钥失败: %v", userID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取API密钥失败"))
+		return
+	}
+
+	response := make([]types.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, toAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, types.SuccessResponse(response, "获取API密钥成功"))
+}
+
+// CreateAPIKey 创建密钥，明文只在本次响应中返回
+func (kc *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "密钥名称不能为空"))
+		return
+	}
+
+	seen := make(map[string]bool)
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !models.IsKnownScope(scope) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "未知的授权范围: "+scope))
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	user, ok := currentActiveUser(kc.db, c)
+	if !ok {
+		return
+	}
+
+	var active int64
+	kc.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", user.ID, time.Now()).
+		Count(&active)
+	if active >= maxActiveAPIKeys {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "API密钥数量已达上限，请先撤销不再使用的密钥"))
+		return
+	}
+
+	plain, prefix, hash, err := middleware.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成API密钥失败"))
+		return
+	}
+
+	key := models.APIKey{
+		UserID:  user.ID,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := kc.db.Create(&key).Error; err != nil {
+		logger.ErrorLogger.Printf("用户 %d 创建API密钥失败: %v", user.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成API密钥失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("用户 %d 创建了API密钥 %d (%s)", user.ID, key.ID, key.Scopes)
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{
+		"key":     plain,
+		"api_key": toAPIKeyResponse(key),
+	}, "API密钥已创建，请立即保存，之后将无法再次查看"))
+}
+
+// RevokeAPIKey 撤销当前用户的密钥，立即失效
+func (kc *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的密钥ID"))
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result := kc.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logger.ErrorLogger.Printf("撤销API密钥 %d 失败: %v", id, result.Error)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "撤销API密钥失败"))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "API密钥不存在"))
+		return
+	}
+
+	logger.InfoLogger.Printf("用户 %v 撤销了API密钥 %d", userID, id)
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": id}, "API密钥已撤销"))
+}
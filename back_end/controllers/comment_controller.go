@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 每条顶层评论默认附带的回复数量
+const commentReplyPreviewSize = 3
+
+type CommentController struct {
+	db *gorm.DB
+}
+
+func NewCommentController(db *gorm.DB) *CommentController {
+	return &CommentController{db: db}
+}
+
+type CreateCommentRequest struct {
+	Content  string `json:"content" binding:"required,max=1000"`
+	ParentID uint   `json:"parent_id"` // 回复的评论ID，为空表示发表顶层评论
+}
+
+type GetCommentsRequest struct {
+	Cursor uint `form:"cursor"` // 上一页返回的 next_cursor，为空表示第一页
+	Limit  int  `form:"limit,default=10"`
+}
+
+func toUserResponse(user models.User) types.UserResponse {
+	return types.UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Nickname:  user.Nickname,
+		AvatarURL: user.AvatarURL,
+	}
+}
+
+func toCommentResponse(comment models.Comment) types.CommentResponse {
+	response := types.CommentResponse{
+		ID:        comment.ID,
+		GuideID:   comment.GuideID,
+		User:      toUserResponse(comment.User),
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt.Unix(),
+	}
+	if comment.ParentID != nil {
+		response.ParentID = *comment.ParentID
+	}
+	if comment.ReplyToUser != nil {
+		replyTo := toUserResponse(*comment.ReplyToUser)
+		response.ReplyToUser = &replyTo
+	}
+	return response
+}
+
+// findPublishedGuide 查找已发布的攻略，失败时已写入错误响应
+func (cc *CommentController) findPublishedGuide(c *gin.Context) (*models.TravelGuide, bool) {
+	guideID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的攻略ID"))
+		return nil, false
+	}
+
+	var guide models.TravelGuide
+	if err := cc.db.Scopes(publishedGuides).First(&guide, guideID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略不存在"))
+		return nil, false
+	}
+	return &guide, true
+}
+
+// CreateComment 发表评论或回复
+func (cc *CommentController) CreateComment(c *gin.Context) {
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "评论内容不能为空"))
+		return
+	}
+
+	user, ok := currentActiveUser(cc.db, c)
+	if !ok {
+		return
+	}
+
+	guide, ok := cc.findPublishedGuide(c)
+	if !ok {
+		return
+	}
+
+	comment := models.Comment{
+		GuideID: guide.ID,
+		UserID:  user.ID,
+		Content: content,
+	}
+
+	if req.ParentID != 0 {
+		var parent models.Comment
+		if err := cc.db.Where("guide_id = ?", guide.ID).First(&parent, req.ParentID).Error; err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "回复的评论不存在"))
+			return
+		}
+
+		// 只支持一层回复，回复某条回复时挂到其顶层评论下
+		rootID := parent.ID
+		if parent.ParentID != nil {
+			rootID = *parent.ParentID
+		}
+		comment.ParentID = &rootID
+		comment.ReplyToUserID = &parent.UserID
+	}
+
+	if err := cc.db.Create(&comment).Error; err != nil {
+		logger.ErrorLogger.Printf("创建评论失败，攻略ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "发表评论失败"))
+		return
+	}
+
+	cc.db.Preload("User").Preload("ReplyToUser").First(&comment, comment.ID)
+
+	logger.InfoLogger.Printf("用户 %d 评论攻略 %d，评论ID: %d", user.ID, guide.ID, comment.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(toCommentResponse(comment), "发表评论成功"))
+}
+
+// loadReplyPreviews 一次查询出一批顶级评论的回复数，以及每条评论最早的几条回复
+func (cc *CommentController) loadReplyPreviews(comments []models.Comment) (map[uint]int64, map[uint][]models.Comment, error) {
+	counts := make(map[uint]int64, len(comments))
+	previews := make(map[uint][]models.Comment, len(comments))
+	if len(comments) == 0 {
+		return counts, previews, nil
+	}
+
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+
+	var rows []struct {
+		ParentID uint
+		Count    int64
+	}
+	if err := cc.db.Model(&models.Comment{}).
+		Select("parent_id, COUNT(*) AS count").
+		Where("parent_id IN ?", ids).
+		Group("parent_id").
+		Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		counts[row.ParentID] = row.Count
+	}
+
+	// 每条评论只取 id 最小的 commentReplyPreviewSize 条回复
+	var replies []models.Comment
+	if err := cc.db.Preload("User").Preload("ReplyToUser").
+		Where("parent_id IN ?", ids).
+		Where(`(SELECT COUNT(*) FROM comments earlier
+			WHERE earlier.parent_id = comments.parent_id AND earlier.id < comments.id AND earlier.deleted_at IS NULL) < ?`,
+			commentReplyPreviewSize).
+		Order("id ASC").
+		Find(&replies).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, reply := range replies {
+		previews[*reply.ParentID] = append(previews[*reply.ParentID], reply)
+	}
+	return counts, previews, nil
+}
+
+// GetComments 获取攻略的顶层评论（按时间倒序），每条附带最早的几条回复
+func (cc *CommentController) GetComments(c *gin.Context) {
+	var req GetCommentsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 50 {
+		req.Limit = 10
+	}
+
+	guide, ok := cc.findPublishedGuide(c)
+	if !ok {
+		return
+	}
+
+	query := cc.db.Preload("User").
+		Where("guide_id = ? AND parent_id IS NULL", guide.ID)
+	if req.Cursor > 0 {
+		query = query.Where("id < ?", req.Cursor)
+	}
+
+	var comments []models.Comment
+	if err := query.Order("id DESC").Limit(req.Limit + 1).Find(&comments).Error; err != nil {
+		logger.ErrorLogger.Printf("获取评论失败，攻略ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取评论失败"))
+		return
+	}
+
+	hasMore := false
+	if len(comments) > req.Limit {
+		hasMore = true
+		comments = comments[:req.Limit]
+	}
+
+	replyCounts, replies, err := cc.loadReplyPreviews(comments)
+	if err != nil {
+		logger.ErrorLogger.Printf("获取评论回复失败，攻略ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取评论失败"))
+		return
+	}
+
+	responses := make([]types.CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		response := toCommentResponse(comment)
+		response.ReplyCount = replyCounts[comment.ID]
+		for _, reply := range replies[comment.ID] {
+			response.Replies = append(response.Replies, toCommentResponse(reply))
+		}
+		responses = append(responses, response)
+	}
+
+	var nextCursor uint
+	if hasMore {
+		nextCursor = comments[len(comments)-1].ID
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.CursorResponse{
+			List:       responses,
+			NextCursor: nextCursor,
+			HasMore:    hasMore,
+		},
+		"获取评论成功",
+	))
+}
+
+// GetCommentReplies 获取某条顶层评论下的回复（按时间正序）
+func (cc *CommentController) GetCommentReplies(c *gin.Context) {
+	var req GetCommentsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 50 {
+		req.Limit = 10
+	}
+
+	guide, ok := cc.findPublishedGuide(c)
+	if !ok {
+		return
+	}
+
+	var parent models.Comment
+	if err := cc.db.Where("guide_id = ? AND parent_id IS NULL", guide.ID).First(&parent, c.Param("comment_id")).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "评论不存在"))
+		return
+	}
+
+	query := cc.db.Preload("User").Preload("ReplyToUser").Where("parent_id = ?", parent.ID)
+	if req.Cursor > 0 {
+		query = query.Where("id > ?", req.Cursor)
+	}
+
+	var replies []models.Comment
+	if err := query.Order("id ASC").Limit(req.Limit + 1).Find(&replies).Error; err != nil {
+		logger.ErrorLogger.Printf("获取评论回复失败，评论ID %d: %v", parent.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取回复失败"))
+		return
+	}
+
+	hasMore := false
+	if len(replies) > req.Limit {
+		hasMore = true
+		replies = replies[:req.Limit]
+	}
+
+	responses := make([]types.CommentResponse, 0, len(replies))
+	for _, reply := range replies {
+		responses = append(responses, toCommentResponse(reply))
+	}
+
+	var nextCursor uint
+	if hasMore {
+		nextCursor = replies[len(replies)-1].ID
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.CursorResponse{
+			List:       responses,
+			NextCursor: nextCursor,
+			HasMore:    hasMore,
+		},
+		"获取回复成功",
+	))
+}
+
+// DeleteComment 删除评论（评论作者或管理员），删除顶层评论时一并删除其回复
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	var comment models.Comment
+	if err := cc.db.Where("guide_id = ?", c.Param("id")).First(&comment, c.Param("comment_id")).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "评论不存在"))
+		return
+	}
+
+	if comment.UserID != userID && !hasPermission(cc.db, c, models.PermCommentDeleteAny) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无权删除该评论"))
+		return
+	}
+
+	err := cc.db.Transaction(func(tx *gorm.DB) error {
+		if comment.ParentID == nil {
+			if err := tx.Where("parent_id = ?", comment.ID).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&comment).Error
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("删除评论失败，ID %d: %v", comment.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "删除评论失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("评论删除成功，ID: %d", comment.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": comment.ID}, "删除评论成功"))
+}
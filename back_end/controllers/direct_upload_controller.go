@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"travel_guide/config"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/imageproc"
+	"travel_guide/utils/logger"
+	"travel_guide/utils/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 直传地址过期后仍允许确认一段时间，给较慢的上传留出余量
+const directUploadConfirmGrace = 10 * time.Minute
+
+// 未配置有效期时直传地址的默认有效期
+const defaultDirectUploadExpiry = 15 * time.Minute
+
+// PresignUploadRequest 申请直传地址，ContentType 和 Size 为客户端要上传的文件信息
+type PresignUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required,min=1"`
+}
+
+// ConfirmUploadRequest 确认直传完成，Key 为申请直传地址时返回的对象名
+type ConfirmUploadRequest struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// directUploadExpiry 直传地址的有效期
+func directUploadExpiry() time.Duration {
+	seconds := config.AppConfig.ImageConfig.DirectUploadExpiresIn
+	if seconds <= 0 {
+		return defaultDirectUploadExpiry
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// PresignUpload 签发直传地址，客户端把图片直接上传到存储后再调用 ConfirmUpload
+func (uc *UploadController) PresignUpload(c *gin.Context) {
+	var req PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	presigner, ok := uc.store.(storage.Presigner)
+	if !ok {
+		c.JSON(http.StatusOK, types.CodedErrorResponse("direct_upload_unsupported", "当前存储不支持直传，请使用普通上传"))
+		return
+	}
+
+	format, ok := imageproc.FormatFromContentType(req.ContentType)
+	if !ok {
+		c.JSON(http.StatusOK, types.CodedErrorResponse(imageproc.CodeUnsupported, "只支持 JPEG、PNG、GIF、WebP 格式的图片"))
+		return
+	}
+	if limits := imageLimits(); limits.MaxBytes > 0 && req.Size > limits.MaxBytes {
+		c.JSON(http.StatusOK, types.CodedErrorResponse(imageproc.CodeTooLarge, "图片文件过大"))
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	key := newImageKey(format)
+	signed, err := presigner.PresignPut(c.Request.Context(), key, format.ContentType(), req.Size, directUploadExpiry())
+	if err != nil {
+		logger.ErrorLogger.Printf("生成直传地址失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成上传地址失败"))
+		return
+	}
+
+	pending := models.PendingUpload{
+		UserID:      userID.(uint),
+		ObjectKey:   key,
+		ContentType: format.ContentType(),
+		Size:        req.Size,
+		ExpiresAt:   signed.ExpiresAt.Add(directUploadConfirmGrace),
+	}
+	if err := uc.db.Create(&pending).Error; err != nil {
+		logger.ErrorLogger.Printf("保存直传记录失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成上传地址失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("用户 %v 申请直传，对象名: %s", userID, key)
+	c.JSON(http.StatusOK, types.SuccessResponse(types.PresignedUploadResponse{
+		Key:       key,
+		Method:    signed.Method,
+		URL:       signed.URL,
+		Headers:   signed.Headers,
+		ExpiresAt: signed.ExpiresAt.Unix(),
+	}, "获取上传地址成功"))
+}
+
+// ConfirmUpload 确认直传完成：读取对象按内容校验、去除元数据并生成缩略图，登记为当前用户的图片
+func (uc *UploadController) ConfirmUpload(c *gin.Context) {
+	var req ConfirmUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	var pending models.PendingUpload
+	if err := uc.db.Where("object_key = ? AND user_id = ?", req.Key, userID).First(&pending).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "上传记录不存在或已确认"))
+		return
+	}
+	if pending.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "上传已过期，请重新上传"))
+		return
+	}
+
+	// 对象还不存在时保留记录，客户端上传完成后可以再次确认
+	ctx := c.Request.Context()
+	body, err := uc.store.Get(ctx, pending.ObjectKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "图片尚未上传完成"))
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Printf("读取直传对象 %s 失败: %v", pending.ObjectKey, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "确认上传失败"))
+		return
+	}
+	defer body.Close()
+
+	// 先删除记录，并发的重复确认只有一个能继续
+	result := uc.db.Where("id = ?", pending.ID).Delete(&models.PendingUpload{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "上传记录不存在或已确认"))
+		return
+	}
+
+	// 客户端上传的对象在确认后一律删除：失败时需要重新上传，成功时原图另存到新的对象名，
+	// 直传地址在有效期内仍可写入原对象名，不能让它覆盖已校验过的图片
+	discard := func() {
+		if err := uc.store.Delete(ctx, pending.ObjectKey); err != nil {
+			logger.ErrorLogger.Printf("删除直传对象 %s 失败: %v", pending.ObjectKey, err)
+		}
+	}
+
+	// 多读一个字节用于判断是否超过限制，部分存储的签名地址无法限制上传大小
+	reader := io.Reader(body)
+	if limits := imageLimits(); limits.MaxBytes > 0 {
+		reader = io.LimitReader(body, limits.MaxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		logger.ErrorLogger.Printf("读取直传对象 %s 失败: %v", pending.ObjectKey, err)
+		discard()
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "确认上传失败，请重新上传"))
+		return
+	}
+
+	data, info, err := prepareImage(data)
+	if err != nil {
+		discard()
+		var imgErr *imageproc.Error
+		if errors.As(err, &imgErr) {
+			logger.ErrorLogger.Printf("直传图片校验失败: %s (%s)", imgErr.Code, pending.ObjectKey)
+			c.JSON(http.StatusOK, types.CodedErrorResponse(imgErr.Code, imgErr.Message))
+			return
+		}
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "确认上传失败，请重新上传"))
+		return
+	}
+
+	image, err := uc.storeImage(ctx, pending.UserID, newImageKey(info.Format), data, info)
+	discard()
+	if err != nil {
+		logger.ErrorLogger.Printf("保存直传图片 %s 失败: %v", pending.ObjectKey, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "确认上传失败，请重新上传"))
+		return
+	}
+
+	logger.InfoLogger.Printf("直传图片确认成功，访问URL: %s", image.URL)
+	c.JSON(http.StatusOK, types.SuccessResponse(toImageResponse(*image), "上传成功"))
+}
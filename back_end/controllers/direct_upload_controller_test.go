@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel_guide/models"
+	"travel_guide/utils/imageproc"
+	"travel_guide/utils/storage"
+)
+
+// preparePendingUpload 登记一条直传记录并模拟客户端把 data 写入对应的对象名
+func preparePendingUpload(t *testing.T, uc *UploadController, userID uint, data []byte) models.PendingUpload {
+	t.Helper()
+	pending := models.PendingUpload{
+		UserID:      userID,
+		ObjectKey:   newImageKey(imageproc.FormatPNG),
+		ContentType: imageproc.FormatPNG.ContentType(),
+		Size:        int64(len(data)),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := uc.db.Create(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.store.Put(context.Background(), pending.ObjectKey, bytes.NewReader(data), int64(len(data)), pending.ContentType); err != nil {
+		t.Fatal(err)
+	}
+	return pending
+}
+
+func objectExists(t *testing.T, store storage.Storage, key string) bool {
+	t.Helper()
+	body, err := store.Get(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	return true
+}
+
+func TestConfirmUploadStoresUnderNewKey(t *testing.T) {
+	db := newTestDB(t)
+	store, err := storage.NewLocalStorage(t.TempDir(), "/uploads", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := NewUploadController(db, store)
+	user := createTestUser(t, db, "alice", models.RoleUser)
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	pending := preparePendingUpload(t, uc, user.ID, buf.Bytes())
+
+	resp := performJSON(t, http.MethodPost, "/api/upload/confirm", "/api/upload/confirm",
+		ConfirmUploadRequest{Key: pending.ObjectKey}, user.ID, uc.ConfirmUpload)
+	if resp.Code != 0 {
+		t.Fatalf("ConfirmUpload: %+v", resp)
+	}
+
+	var stored models.Image
+	if err := db.Where("user_id = ?", user.ID).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 直传地址仍可写入原对象名，确认后的图片不能使用它
+	if stored.ObjectKey == pending.ObjectKey {
+		t.Fatalf("confirmed image reuses the presigned key %s", pending.ObjectKey)
+	}
+	if !objectExists(t, store, stored.ObjectKey) {
+		t.Fatalf("confirmed image %s was not stored", stored.ObjectKey)
+	}
+	if objectExists(t, store, pending.ObjectKey) {
+		t.Fatalf("client-written object %s was kept", pending.ObjectKey)
+	}
+}
+
+func TestConfirmUploadDiscardsInvalidObject(t *testing.T) {
+	db := newTestDB(t)
+	store, err := storage.NewLocalStorage(t.TempDir(), "/uploads", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := NewUploadController(db, store)
+	user := createTestUser(t, db, "alice", models.RoleUser)
+	pending := preparePendingUpload(t, uc, user.ID, []byte("not an image"))
+
+	resp := performJSON(t, http.MethodPost, "/api/upload/confirm", "/api/upload/confirm",
+		ConfirmUploadRequest{Key: pending.ObjectKey}, user.ID, uc.ConfirmUpload)
+	if resp.Code == 0 {
+		t.Fatal("invalid image was confirmed")
+	}
+	if objectExists(t, store, pending.ObjectKey) {
+		t.Fatalf("invalid object %s was kept", pending.ObjectKey)
+	}
+	var count int64
+	db.Model(&models.Image{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("images = %d, want 0", count)
+	}
+}
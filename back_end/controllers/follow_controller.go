@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FollowController struct {
+	db *gorm.DB
+}
+
+func NewFollowController(db *gorm.DB) *FollowController {
+	return &FollowController{db: db}
+}
+
+// findTargetUser 查找路径参数中的用户，失败时已写入错误响应
+func (fc *FollowController) findTargetUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return nil, false
+	}
+
+	var user models.User
+	if err := fc.db.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return nil, false
+	}
+	return &user, true
+}
+
+// FollowUser 关注用户
+func (fc *FollowController) FollowUser(c *gin.Context) {
+	follower, ok := currentActiveUser(fc.db, c)
+	if !ok {
+		return
+	}
+
+	followee, ok := fc.findTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if follower.ID == followee.ID {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不能关注自己"))
+		return
+	}
+
+	follow := models.UserFollow{FollowerID: follower.ID, FolloweeID: followee.ID}
+	if err := fc.db.Where(&follow).FirstOrCreate(&follow).Error; err != nil {
+		logger.ErrorLogger.Printf("用户 %d 关注 %d 失败: %v", follower.ID, followee.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "关注失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": followee.ID, "following": true}, "关注成功"))
+}
+
+// UnfollowUser 取消关注用户
+func (fc *FollowController) UnfollowUser(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	followeeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return
+	}
+
+	if err := fc.db.Where("follower_id = ? AND followee_id = ?", userID, followeeID).
+		Delete(&models.UserFollow{}).Error; err != nil {
+		logger.ErrorLogger.Printf("用户 %v 取消关注 %d 失败: %v", userID, followeeID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "取消关注失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": followeeID, "following": false}, "取消关注成功"))
+}
+
+// listFollowUsers 分页列出关注关系另一端的用户
+// joinColumn 为与 users.id 关联的列，filterColumn 为按路径用户过滤的列
+func (fc *FollowController) listFollowUsers(c *gin.Context, joinColumn, filterColumn, message string) {
+	user, ok := fc.findTargetUser(c)
+	if !ok {
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := fc.db.Model(&models.User{}).
+		Joins("JOIN user_follows ON user_follows."+joinColumn+" = users.id").
+		Where("user_follows."+filterColumn+" = ?", user.ID)
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	query.Order("user_follows.created_at DESC").
+		Offset(offset).
+		Limit(limit + 1). // 多查询一条用于判断是否还有更多
+		Find(&users)
+
+	hasMore := false
+	if len(users) > limit {
+		hasMore = true
+		users = users[:limit]
+	}
+
+	userResponses := make([]types.UserResponse, 0, len(users))
+	for _, u := range users {
+		userResponses = append(userResponses, toUserResponse(u))
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.PaginationResponse{
+			List:    userResponses,
+			Total:   total,
+			HasMore: hasMore,
+		},
+		message,
+	))
+}
+
+// GetFollowers 获取用户的粉丝列表
+func (fc *FollowController) GetFollowers(c *gin.Context) {
+	fc.listFollowUsers(c, "follower_id", "followee_id", "获取粉丝列表成功")
+}
+
+// GetFollowing 获取用户的关注列表
+func (fc *FollowController) GetFollowing(c *gin.Context) {
+	fc.listFollowUsers(c, "followee_id", "follower_id", "获取关注列表成功")
+}
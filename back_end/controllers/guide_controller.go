@@ -1,7 +1,7 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -16,9 +16,7 @@ import (
 
 // 转换单个攻略
 func toGuideResponse(guide models.TravelGuide) types.GuideResponse {
-	// 解析 images
-	var images []string
-	_ = json.Unmarshal([]byte(guide.Images), &images)
+	images := toGuideImageResponses(guide.Images)
 
 	// 转换 tags
 	var tags []types.TagResponse
@@ -37,16 +35,21 @@ func toGuideResponse(guide models.TravelGuide) types.GuideResponse {
 		AvatarURL: guide.User.AvatarURL,
 	}
 
-	return types.GuideResponse{
+	response := types.GuideResponse{
 		ID:          guide.ID,
 		Title:       guide.Title,
 		Content:     guide.Content,
 		Images:      images,
 		UserID:      guide.UserID,
 		User:        userResponse,
+		Status:      string(guide.Status),
 		PublishedAt: guide.PublishedAt.Unix(),
 		Tags:        tags,
 	}
+	if guide.DeletedAt.Valid {
+		response.DeletedAt = guide.DeletedAt.Time.Unix()
+	}
+	return response
 }
 
 // 转换攻略列表
@@ -60,9 +63,7 @@ func toGuideResponseList(guides []models.TravelGuide) []types.GuideResponse {
 
 // 添加一个转换函数
 func toCreateGuideResponse(guide models.TravelGuide) types.CreateGuideResponse {
-	// 解析 images
-	var images []string
-	_ = json.Unmarshal([]byte(guide.Images), &images)
+	images := toGuideImageResponses(guide.Images)
 
 	// 转换 tags
 	var tags []types.TagResponse
@@ -78,11 +79,65 @@ func toCreateGuideResponse(guide models.TravelGuide) types.CreateGuideResponse {
 		Title:       guide.Title,
 		Content:     guide.Content,
 		Images:      images,
+		Status:      string(guide.Status),
 		PublishedAt: guide.PublishedAt.Unix(),
 		Tags:        tags,
 	}
 }
 
+// publishedGuides 只保留已发布且作者账号未注销的攻略，用于所有公开的列表查询
+func publishedGuides(db *gorm.DB) *gorm.DB {
+	return db.Where("travel_guides.status = ?", models.GuideStatusPublished).
+		Where("travel_guides.user_id NOT IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)")
+}
+
+// resolveGuideStatus 根据请求的状态和发布时间计算攻略最终的状态与发布时间
+// current 为 nil 表示新建攻略；status 为空时按立即发布处理，发布时间晚于当前时间时转为定时发布
+func resolveGuideStatus(status models.GuideStatus, publishedAt *time.Time, current *models.TravelGuide) (models.GuideStatus, time.Time, error) {
+	now := time.Now()
+	if status == models.GuideStatusScheduled && publishedAt == nil && current != nil {
+		publishedAt = &current.PublishedAt
+	}
+
+	switch status {
+	case "", models.GuideStatusPublished, models.GuideStatusScheduled:
+		if publishedAt != nil && publishedAt.After(now) {
+			return models.GuideStatusScheduled, *publishedAt, nil
+		}
+		if status == models.GuideStatusScheduled {
+			return "", time.Time{}, errors.New("定时发布时间必须晚于当前时间")
+		}
+		if current != nil && current.Status == models.GuideStatusPublished {
+			return models.GuideStatusPublished, current.PublishedAt, nil
+		}
+		return models.GuideStatusPublished, now, nil
+	case models.GuideStatusDraft:
+		if publishedAt != nil {
+			return models.GuideStatusDraft, *publishedAt, nil
+		}
+		if current != nil {
+			return models.GuideStatusDraft, current.PublishedAt, nil
+		}
+		return models.GuideStatusDraft, now, nil
+	case models.GuideStatusArchived:
+		if current == nil {
+			return "", time.Time{}, errors.New("不能直接创建已归档的攻略")
+		}
+		return models.GuideStatusArchived, current.PublishedAt, nil
+	default:
+		return "", time.Time{}, errors.New("无效的攻略状态")
+	}
+}
+
+// unixTime 将请求中的秒级时间戳转换为时间
+func unixTime(seconds *int64) *time.Time {
+	if seconds == nil {
+		return nil
+	}
+	t := time.Unix(*seconds, 0)
+	return &t
+}
+
 type GuideController struct {
 	db *gorm.DB
 }
@@ -92,10 +147,12 @@ func NewGuideController(db *gorm.DB) *GuideController {
 }
 
 type CreateGuideRequest struct {
-	Title   string   `json:"title" binding:"required"`
-	Content string   `json:"content" binding:"required"`
-	Images  []string `json:"images"`
-	Tags    []string `json:"tags"`
+	Title       string   `json:"title" binding:"required"`
+	Content     string   `json:"content" binding:"required"`
+	Images      []string `json:"images"`
+	Tags        []string `json:"tags"`
+	Status      string   `json:"status"`       // draft/published/scheduled，默认立即发布
+	PublishedAt *int64   `json:"published_at"` // 秒级时间戳，晚于当前时间时定时发布
 }
 
 // 创建攻略
@@ -124,7 +181,7 @@ func (gc *GuideController) CreateGuide(c *gin.Context) {
 		return
 	}
 
-	if user.Status == models.StatusBanned {
+	if user.IsBanned(time.Now()) {
 		logger.ErrorLogger.Printf("用户已被禁用，无法创建攻略")
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户已被禁用"))
 		return
@@ -132,44 +189,50 @@ func (gc *GuideController) CreateGuide(c *gin.Context) {
 
 	logger.InfoLogger.Printf("用户 %v 开始创建攻略", userID)
 
-	imagesJSON, err := json.Marshal(req.Images)
+	status, publishedAt, err := resolveGuideStatus(models.GuideStatus(req.Status), unixTime(req.PublishedAt), nil)
 	if err != nil {
-		logger.ErrorLogger.Printf("图片JSON序列化失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+
+	images, err := buildGuideImages(gc.db, 0, req.Images, userID.(uint))
+	if err != nil {
+		logger.ErrorLogger.Printf("处理图片列表失败: %v", err)
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "处理图片失败"))
 		return
 	}
-	logger.InfoLogger.Printf("处理图片列表: %s", string(imagesJSON))
 
 	guide := models.TravelGuide{
 		Title:       req.Title,
 		Content:     req.Content,
-		Images:      string(imagesJSON),
 		UserID:      userID.(uint),
-		PublishedAt: time.Now(),
+		Status:      status,
+		PublishedAt: publishedAt,
 	}
 
-	// Create or get tags
-	var tags []models.Tag
-	for _, tagName := range req.Tags {
-		var tag models.Tag
-		gc.db.FirstOrCreate(&tag, models.Tag{Name: tagName})
-		tags = append(tags, tag)
-	}
-
-	guide.Tags = tags
-
 	// 使用事务来确保数据一致性
 	err = gc.db.Transaction(func(tx *gorm.DB) error {
+		tags, err := resolveTags(tx, req.Tags)
+		if err != nil {
+			return err
+		}
+		guide.Tags = tags
+
 		// 创建攻略
 		if err := tx.Create(&guide).Error; err != nil {
 			return err
 		}
+		if err := replaceGuideImages(tx, guide.ID, images); err != nil {
+			return err
+		}
 
-		// 查询完整信息，只加载标签信息
-		if err := tx.Preload("Tags").First(&guide, guide.ID).Error; err != nil {
+		// 查询完整信息，只加载标签和图片信息
+		if err := tx.Preload("Tags").Scopes(withGuideImages).First(&guide, guide.ID).Error; err != nil {
 			return err
 		}
-		return nil
+
+		// 记录初始版本
+		return createGuideRevision(tx, &guide, guide.UserID)
 	})
 
 	if err != nil {
@@ -209,12 +272,19 @@ func (gc *GuideController) SearchGuides(c *gin.Context) {
 
 	// 构建基础查询
 	query := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
 		Preload("User").
-		Preload("Tags")
+		Preload("Tags").
+		Scopes(withGuideImages)
+
+	// 有权限的用户可以查看已删除的攻略
+	if c.Query("include_deleted") == "true" && hasPermission(gc.db, c, models.PermGuideViewHidden) {
+		query = query.Unscoped()
+	}
 
 	// 添加关键词搜索条件
 	if keyword != "" {
-		query = query.Where("title LIKE ? OR content LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+		query = query.Where("(title LIKE ? OR content LIKE ?)", "%"+keyword+"%", "%"+keyword+"%")
 	}
 
 	// 如果有标签过滤条件，添加标签过滤
@@ -290,6 +360,7 @@ func (gc *GuideController) SearchGuides(c *gin.Context) {
 	for _, guide := range guides {
 		guideResponses = append(guideResponses, toGuideResponse(guide))
 	}
+	gc.fillGuideStats(c, guideResponses)
 
 	c.JSON(http.StatusOK, types.SuccessResponse(
 		gin.H{
@@ -343,6 +414,8 @@ type GetGuidesRequest struct {
 	Tag    string `form:"tag"`
 	Offset int    `form:"offset,default=0"` // 改用 offset
 	Limit  int    `form:"limit,default=10"` // page_size 改名为 limit
+
+	IncludeDeleted bool `form:"include_deleted"` // 仅管理员有效
 }
 
 // 重命名为 GetGuides，因为是获取攻略列表
@@ -362,8 +435,15 @@ func (gc *GuideController) GetGuides(c *gin.Context) {
 	}
 
 	query := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
 		Preload("User"). // 加载用户信息
-		Preload("Tags")  // 加载标签信息
+		Preload("Tags"). // 加载标签信息
+		Scopes(withGuideImages)
+
+	// 有权限的用户可以查看已删除的攻略
+	if req.IncludeDeleted && hasPermission(gc.db, c, models.PermGuideViewHidden) {
+		query = query.Unscoped()
+	}
 
 	// 如果有tag参数，添加tag过滤
 	if req.Tag != "" {
@@ -392,6 +472,7 @@ func (gc *GuideController) GetGuides(c *gin.Context) {
 	for _, guide := range guides {
 		guideResponses = append(guideResponses, toGuideResponse(guide))
 	}
+	gc.fillGuideStats(c, guideResponses)
 
 	c.JSON(http.StatusOK, types.Response{
 		Code: 0,
@@ -410,14 +491,471 @@ func (gc *GuideController) GetGuideDetail(c *gin.Context) {
 	logger.InfoLogger.Printf("获取攻略详情，ID: %s", id)
 
 	var guide models.TravelGuide
-	if err := gc.db.Preload("User").Preload("Tags").First(&guide, id).Error; err != nil {
+	if err := gc.db.Preload("User").Preload("Tags").Scopes(withGuideImages).First(&guide, id).Error; err != nil {
 		logger.ErrorLogger.Printf("获取攻略详情失败，ID %s: %v", id, err)
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略不存在"))
 		return
 	}
 
+	// 未发布或作者已注销的攻略只有作者和有权限的用户可见（作者已注销时 Preload 不到 User）
+	if guide.Status != models.GuideStatusPublished || guide.User.ID == 0 {
+		userID, _ := c.Get("user_id")
+		if userID != guide.UserID && !hasPermission(gc.db, c, models.PermGuideViewHidden) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略不存在"))
+			return
+		}
+	}
+
 	logger.InfoLogger.Printf("成功获取攻略详情，ID: %s", id)
-	c.JSON(http.StatusOK, types.SuccessResponse(toGuideResponse(guide), "获取攻略成功"))
+	c.JSON(http.StatusOK, types.SuccessResponse(gc.guideResponseWithStats(c, guide), "获取攻略成功"))
+}
+
+type GetMyGuidesRequest struct {
+	Status string `form:"status"`
+	Offset int    `form:"offset,default=0"`
+	Limit  int    `form:"limit,default=10"`
+}
+
+// GetMyGuides 获取当前用户自己的攻略，包括草稿和定时发布的攻略
+func (gc *GuideController) GetMyGuides(c *gin.Context) {
+	var req GetMyGuidesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	query := gc.db.Model(&models.TravelGuide{}).
+		Preload("User").
+		Preload("Tags").
+		Scopes(withGuideImages).
+		Where("user_id = ?", userID)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var guides []models.TravelGuide
+	query.Order("updated_at DESC").Offset(req.Offset).Limit(limit + 1).Find(&guides) // 多查询一条用于判断是否还有更多
+
+	hasMore := false
+	if len(guides) > limit {
+		hasMore = true
+		guides = guides[:limit]
+	}
+
+	guideResponses := make([]types.GuideResponse, 0, len(guides))
+	for _, guide := range guides {
+		guideResponses = append(guideResponses, toGuideResponse(guide))
+	}
+	gc.fillGuideStats(c, guideResponses)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.PaginationResponse{
+			List:    guideResponses,
+			Total:   total,
+			HasMore: hasMore,
+		},
+		"获取我的攻略成功",
+	))
+}
+
+// GetUserGuides 获取指定用户已发布的攻略，按发布时间倒序
+func (gc *GuideController) GetUserGuides(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return
+	}
+
+	var user models.User
+	if err := gc.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
+		Preload("User").
+		Preload("Tags").
+		Scopes(withGuideImages).
+		Where("user_id = ?", user.ID)
+
+	var total int64
+	query.Count(&total)
+
+	var guides []models.TravelGuide
+	query.Order("published_at DESC").
+		Offset(offset).
+		Limit(limit + 1). // 多查询一条用于判断是否还有更多
+		Find(&guides)
+
+	hasMore := false
+	if len(guides) > limit {
+		hasMore = true
+		guides = guides[:limit]
+	}
+
+	guideResponses := make([]types.GuideResponse, 0, len(guides))
+	for _, guide := range guides {
+		guideResponses = append(guideResponses, toGuideResponse(guide))
+	}
+	gc.fillGuideStats(c, guideResponses)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.PaginationResponse{
+			List:    guideResponses,
+			Total:   total,
+			HasMore: hasMore,
+		},
+		"获取用户攻略成功",
+	))
+}
+
+// GetFollowingFeed 获取当前用户关注的人发布的攻略，按发布时间倒序
+func (gc *GuideController) GetFollowingFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
+		Preload("User").
+		Preload("Tags").
+		Scopes(withGuideImages).
+		Joins("JOIN user_follows ON user_follows.followee_id = travel_guides.user_id").
+		Where("user_follows.follower_id = ?", userID)
+
+	var total int64
+	query.Count(&total)
+
+	var guides []models.TravelGuide
+	query.Order("travel_guides.published_at DESC").
+		Offset(offset).
+		Limit(limit + 1). // 多查询一条用于判断是否还有更多
+		Find(&guides)
+
+	hasMore := false
+	if len(guides) > limit {
+		hasMore = true
+		guides = guides[:limit]
+	}
+
+	guideResponses := make([]types.GuideResponse, 0, len(guides))
+	for _, guide := range guides {
+		guideResponses = append(guideResponses, toGuideResponse(guide))
+	}
+	gc.fillGuideStats(c, guideResponses)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.PaginationResponse{
+			List:    guideResponses,
+			Total:   total,
+			HasMore: hasMore,
+		},
+		"获取关注动态成功",
+	))
+}
+
+type UpdateGuideRequest struct {
+	Title       string   `json:"title" binding:"required"`
+	Content     string   `json:"content" binding:"required"`
+	Images      []string `json:"images"`
+	Tags        []string `json:"tags"`
+	Status      string   `json:"status"` // 为空时保持当前状态
+	PublishedAt *int64   `json:"published_at"`
+}
+
+// PatchGuideRequest 部分更新攻略，未传的字段保持不变
+type PatchGuideRequest struct {
+	Title       *string   `json:"title"`
+	Content     *string   `json:"content"`
+	Images      *[]string `json:"images"`
+	Tags        *[]string `json:"tags"`
+	Status      *string   `json:"status"`
+	PublishedAt *int64    `json:"published_at"`
+}
+
+// findEditableGuide 查找当前用户可以操作的攻略，非作者本人需要拥有 permission 权限
+// 失败时已写入错误响应，调用方直接返回即可
+func (gc *GuideController) findEditableGuide(c *gin.Context, permission string) (*models.TravelGuide, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的攻略ID"))
+		return nil, false
+	}
+
+	user, ok := currentActiveUser(gc.db, c)
+	if !ok {
+		return nil, false
+	}
+
+	var guide models.TravelGuide
+	if err := gc.db.First(&guide, id).Error; err != nil {
+		logger.ErrorLogger.Printf("攻略不存在，ID %d: %v", id, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略不存在"))
+		return nil, false
+	}
+
+	if guide.UserID != user.ID && !hasPermission(gc.db, c, permission) {
+		logger.ErrorLogger.Printf("用户 %d 无权修改攻略 %d", user.ID, id)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无权操作该攻略"))
+		return nil, false
+	}
+
+	return &guide, true
+}
+
+// resolveTags 按名称查找标签，不存在时创建。
+// 已删除或被合并的标签仍占用名称唯一索引，查找时包含它们并恢复，而不是重复插入
+func resolveTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		var tag models.Tag
+		err := tx.Unscoped().Where("name = ?", name).First(&tag).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			tag = models.Tag{Name: name}
+			if err := tx.Create(&tag).Error; err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		case tag.DeletedAt.Valid:
+			if err := tx.Unscoped().Model(&tag).Update("deleted_at", nil).Error; err != nil {
+				return nil, err
+			}
+			tag.DeletedAt = gorm.DeletedAt{}
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// saveGuide 在一个事务中更新攻略字段，并在 tagNames、images 不为 nil 时重写标签和图片，
+// 保存后记录一条由 editorID 产生的版本快照
+func (gc *GuideController) saveGuide(guide *models.TravelGuide, updates map[string]interface{}, tagNames *[]string, images *[]models.GuideImage, editorID uint) error {
+	return gc.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(guide).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		if tagNames != nil {
+			tags, err := resolveTags(tx, *tagNames)
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Exec("DELETE FROM guide_tags WHERE guide_id = ?", guide.ID).Error; err != nil {
+				return err
+			}
+			if len(tags) > 0 {
+				if err := tx.Model(guide).Association("Tags").Append(tags); err != nil {
+					return err
+				}
+			}
+		}
+
+		if images != nil {
+			if err := replaceGuideImages(tx, guide.ID, *images); err != nil {
+				return err
+			}
+		}
+
+		// 重新加载完整信息
+		if err := tx.Preload("User").Preload("Tags").Scopes(withGuideImages).First(guide, guide.ID).Error; err != nil {
+			return err
+		}
+		return createGuideRevision(tx, guide, editorID)
+	})
+}
+
+// UpdateGuide 整体更新攻略
+func (gc *GuideController) UpdateGuide(c *gin.Context) {
+	var req UpdateGuideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.ErrorLogger.Printf("请求数据绑定失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	guide, ok := gc.findEditableGuide(c, models.PermGuideEditAny)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	images, err := buildGuideImages(gc.db, guide.ID, req.Images, userID.(uint))
+	if err != nil {
+		logger.ErrorLogger.Printf("处理图片列表失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "处理图片失败"))
+		return
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	requestedStatus := models.GuideStatus(req.Status)
+	if requestedStatus == "" {
+		requestedStatus = guide.Status
+	}
+	status, publishedAt, err := resolveGuideStatus(requestedStatus, unixTime(req.PublishedAt), guide)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+
+	updates := map[string]interface{}{
+		"title":        req.Title,
+		"content":      req.Content,
+		"status":       status,
+		"published_at": publishedAt,
+	}
+	if err := gc.saveGuide(guide, updates, &tags, &images, userID.(uint)); err != nil {
+		logger.ErrorLogger.Printf("更新攻略失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "更新攻略失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("攻略更新成功，ID: %d", guide.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(gc.guideResponseWithStats(c, *guide), "更新攻略成功"))
+}
+
+// PatchGuide 部分更新攻略
+func (gc *GuideController) PatchGuide(c *gin.Context) {
+	var req PatchGuideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.ErrorLogger.Printf("请求数据绑定失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	if (req.Title != nil && *req.Title == "") || (req.Content != nil && *req.Content == "") {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "标题和内容不能为空"))
+		return
+	}
+
+	guide, ok := gc.findEditableGuide(c, models.PermGuideEditAny)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Content != nil {
+		updates["content"] = *req.Content
+	}
+	var images *[]models.GuideImage
+	if req.Images != nil {
+		built, err := buildGuideImages(gc.db, guide.ID, *req.Images, userID.(uint))
+		if err != nil {
+			logger.ErrorLogger.Printf("处理图片列表失败: %v", err)
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "处理图片失败"))
+			return
+		}
+		images = &built
+	}
+	if req.Status != nil || req.PublishedAt != nil {
+		requestedStatus := guide.Status
+		if req.Status != nil {
+			requestedStatus = models.GuideStatus(*req.Status)
+		}
+		status, publishedAt, err := resolveGuideStatus(requestedStatus, unixTime(req.PublishedAt), guide)
+		if err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+			return
+		}
+		updates["status"] = status
+		updates["published_at"] = publishedAt
+	}
+
+	if err := gc.saveGuide(guide, updates, req.Tags, images, userID.(uint)); err != nil {
+		logger.ErrorLogger.Printf("更新攻略失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "更新攻略失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("攻略部分更新成功，ID: %d", guide.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(gc.guideResponseWithStats(c, *guide), "更新攻略成功"))
+}
+
+// DeleteGuide 软删除攻略
+func (gc *GuideController) DeleteGuide(c *gin.Context) {
+	guide, ok := gc.findEditableGuide(c, models.PermGuideDeleteAny)
+	if !ok {
+		return
+	}
+
+	if err := gc.db.Delete(guide).Error; err != nil {
+		logger.ErrorLogger.Printf("删除攻略失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "删除攻略失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("攻略删除成功，ID: %d", guide.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": guide.ID}, "删除攻略成功"))
+}
+
+// RestoreGuide 恢复已删除的攻略（管理员）
+func (gc *GuideController) RestoreGuide(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的攻略ID"))
+		return
+	}
+
+	var guide models.TravelGuide
+	if err := gc.db.Unscoped().First(&guide, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略不存在"))
+		return
+	}
+
+	if !guide.DeletedAt.Valid {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略未被删除"))
+		return
+	}
+
+	if err := gc.db.Unscoped().Model(&guide).Update("deleted_at", nil).Error; err != nil {
+		logger.ErrorLogger.Printf("恢复攻略失败，ID %d: %v", id, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "恢复攻略失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("攻略恢复成功，ID: %d", id)
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": guide.ID}, "恢复攻略成功"))
 }
 
 type SearchSuggestionResponse struct {
@@ -438,6 +976,7 @@ func (gc *GuideController) GetSearchSuggestions(c *gin.Context) {
 	// 从标题和内容中搜索匹配的关键词
 	var suggestions []string
 	if err := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
 		Select("DISTINCT title").
 		Where("title LIKE ?", "%"+keyword+"%").
 		Limit(5).
@@ -450,6 +989,7 @@ func (gc *GuideController) GetSearchSuggestions(c *gin.Context) {
 	if len(suggestions) < 5 {
 		var contentSuggestions []string
 		if err := gc.db.Model(&models.TravelGuide{}).
+			Scopes(publishedGuides).
 			Select("DISTINCT title").
 			Where("content LIKE ? AND title NOT IN ?", "%"+keyword+"%", suggestions).
 			Limit(5-len(suggestions)).
@@ -496,8 +1036,10 @@ func (gc *GuideController) GetUserRecommendations(c *gin.Context) {
 
 	// 构建基础查询
 	query := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
 		Preload("User").
-		Preload("Tags")
+		Preload("Tags").
+		Scopes(withGuideImages)
 
 	// 检查用户是否有标签
 	var userTagCount int64
@@ -536,6 +1078,7 @@ func (gc *GuideController) GetUserRecommendations(c *gin.Context) {
 	for _, guide := range guides {
 		guideResponses = append(guideResponses, toGuideResponse(guide))
 	}
+	gc.fillGuideStats(c, guideResponses)
 
 	c.JSON(http.StatusOK, types.SuccessResponse(
 		gin.H{
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"travel_guide/models"
+
+	"gorm.io/gorm"
+)
+
+func createTestUser(t *testing.T, db *gorm.DB, username string, role models.UserRole) models.User {
+	t.Helper()
+	user := models.User{Username: username, Nickname: username, Role: role, Status: models.StatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// guideTagIDs 返回攻略关联的标签ID
+func guideTagIDs(t *testing.T, db *gorm.DB, guideID uint) []uint {
+	t.Helper()
+	var ids []uint
+	if err := db.Table("guide_tags").Where("guide_id = ?", guideID).Order("tag_id").Pluck("tag_id", &ids).Error; err != nil {
+		t.Fatalf("load guide tags: %v", err)
+	}
+	return ids
+}
+
+func TestCreateGuideReusesDeletedTag(t *testing.T) {
+	gc := &GuideController{db: newTestDB(t)}
+	user := createTestUser(t, gc.db, "alice", models.RoleUser)
+
+	deleted := models.Tag{Name: "海边"}
+	gc.db.Create(&deleted)
+	gc.db.Delete(&deleted)
+
+	resp := performJSON(t, http.MethodPost, "/api/guides", "/api/guides",
+		CreateGuideRequest{Title: "标题", Content: "内容", Tags: []string{"海边", "古镇"}}, user.ID, gc.CreateGuide)
+	if resp.Code != 0 {
+		t.Fatalf("create guide: %s", resp.Message)
+	}
+
+	var guide models.TravelGuide
+	if err := gc.db.First(&guide).Error; err != nil {
+		t.Fatalf("guide not created: %v", err)
+	}
+	ids := guideTagIDs(t, gc.db, guide.ID)
+	if len(ids) != 2 || ids[0] != deleted.ID || ids[1] == 0 {
+		t.Fatalf("guide tags = %v, want [%d <new>]", ids, deleted.ID)
+	}
+
+	var restored models.Tag
+	if err := gc.db.First(&restored, deleted.ID).Error; err != nil {
+		t.Fatalf("deleted tag was not restored: %v", err)
+	}
+}
+
+func TestPatchGuideReusesDeletedTag(t *testing.T) {
+	gc := &GuideController{db: newTestDB(t)}
+	user := createTestUser(t, gc.db, "alice", models.RoleUser)
+
+	resp := performJSON(t, http.MethodPost, "/api/guides", "/api/guides",
+		CreateGuideRequest{Title: "标题", Content: "内容", Tags: []string{"海边"}}, user.ID, gc.CreateGuide)
+	if resp.Code != 0 {
+		t.Fatalf("create guide: %s", resp.Message)
+	}
+	var guide models.TravelGuide
+	gc.db.First(&guide)
+
+	// 标签被删除后再次使用同名标签
+	var tag models.Tag
+	gc.db.Where("name = ?", "海边").First(&tag)
+	gc.db.Delete(&tag)
+
+	tags := []string{"海边"}
+	target := "/api/guides/" + strconv.Itoa(int(guide.ID))
+	resp = performJSON(t, http.MethodPatch, "/api/guides/:id", target, PatchGuideRequest{Tags: &tags}, user.ID, gc.PatchGuide)
+	if resp.Code != 0 {
+		t.Fatalf("patch guide: %s", resp.Message)
+	}
+	if ids := guideTagIDs(t, gc.db, guide.ID); len(ids) != 1 || ids[0] != tag.ID {
+		t.Fatalf("guide tags = %v, want [%d]", ids, tag.ID)
+	}
+
+	var revisions int64
+	gc.db.Model(&models.GuideRevision{}).Where("guide_id = ?", guide.ID).Count(&revisions)
+	if revisions != 2 {
+		t.Fatalf("revisions = %d, want 2", revisions)
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReorderGuideImagesRequest 调整攻略图片顺序，ImageIDs 为攻略图片ID，需包含攻略的全部图片
+type ReorderGuideImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required"`
+}
+
+// UpdateGuideImageRequest 修改攻略图片的说明，传空字符串表示清除
+type UpdateGuideImageRequest struct {
+	Caption *string `json:"caption" binding:"required,max=500"`
+}
+
+// loadGuideImages 按顺序加载攻略当前的图片
+func (gc *GuideController) loadGuideImages(guide *models.TravelGuide) ([]models.GuideImage, error) {
+	var images []models.GuideImage
+	err := gc.db.Where("guide_id = ?", guide.ID).Order("position, id").Find(&images).Error
+	return images, err
+}
+
+// ReorderGuideImages 调整攻略图片的顺序（作者或有编辑权限的用户）
+func (gc *GuideController) ReorderGuideImages(c *gin.Context) {
+	var req ReorderGuideImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	guide, ok := gc.findEditableGuide(c, models.PermGuideEditAny)
+	if !ok {
+		return
+	}
+
+	current, err := gc.loadGuideImages(guide)
+	if err != nil {
+		logger.ErrorLogger.Printf("获取攻略图片失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "调整图片顺序失败"))
+		return
+	}
+
+	byID := make(map[uint]models.GuideImage, len(current))
+	for _, image := range current {
+		byID[image.ID] = image
+	}
+	if len(req.ImageIDs) != len(current) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "图片列表与攻略当前的图片不一致"))
+		return
+	}
+	images := make([]models.GuideImage, 0, len(req.ImageIDs))
+	for _, id := range req.ImageIDs {
+		image, ok := byID[id]
+		if !ok {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "图片列表与攻略当前的图片不一致"))
+			return
+		}
+		delete(byID, id)
+		images = append(images, image)
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := gc.saveGuide(guide, nil, nil, &images, userID.(uint)); err != nil {
+		logger.ErrorLogger.Printf("调整攻略图片顺序失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "调整图片顺序失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("攻略 %d 的图片顺序已调整", guide.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(toGuideImageResponses(guide.Images), "调整图片顺序成功"))
+}
+
+// UpdateGuideImage 修改攻略图片的说明（作者或有编辑权限的用户）
+func (gc *GuideController) UpdateGuideImage(c *gin.Context) {
+	var req UpdateGuideImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误，图片说明不能超过500个字符"))
+		return
+	}
+
+	imageID, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的图片ID"))
+		return
+	}
+
+	guide, ok := gc.findEditableGuide(c, models.PermGuideEditAny)
+	if !ok {
+		return
+	}
+
+	images, err := gc.loadGuideImages(guide)
+	if err != nil {
+		logger.ErrorLogger.Printf("获取攻略图片失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "修改图片说明失败"))
+		return
+	}
+
+	found := false
+	for i := range images {
+		if images[i].ID == uint(imageID) {
+			images[i].Caption = strings.TrimSpace(*req.Caption)
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "图片不存在"))
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := gc.saveGuide(guide, nil, nil, &images, userID.(uint)); err != nil {
+		logger.ErrorLogger.Printf("修改攻略图片说明失败，攻略 %d 图片 %d: %v", guide.ID, imageID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "修改图片说明失败"))
+		return
+	}
+
+	for _, image := range guide.Images {
+		if image.ID == uint(imageID) {
+			c.JSON(http.StatusOK, types.SuccessResponse(toGuideImageResponse(image), "修改图片说明成功"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "修改图片说明成功"))
+}
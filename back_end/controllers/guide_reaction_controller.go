@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type guideCount struct {
+	GuideID uint
+	Count   int64
+}
+
+// countByGuide 统计 model 对应表中每篇攻略的记录数
+func (gc *GuideController) countByGuide(model interface{}, guideIDs []uint) map[uint]int64 {
+	var rows []guideCount
+	gc.db.Model(model).
+		Select("guide_id, COUNT(*) AS count").
+		Where("guide_id IN ?", guideIDs).
+		Group("guide_id").
+		Scan(&rows)
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.GuideID] = row.Count
+	}
+	return counts
+}
+
+// guideIDsOfUser 返回 table 中属于该用户的攻略ID集合
+func (gc *GuideController) guideIDsOfUser(table string, userID interface{}, guideIDs []uint) map[uint]bool {
+	var ids []uint
+	gc.db.Table(table).
+		Where("user_id = ? AND guide_id IN ?", userID, guideIDs).
+		Pluck("guide_id", &ids)
+
+	result := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result
+}
+
+// fillGuideStats 为攻略列表填充点赞数、收藏数、评论数，以及当前用户的点赞/收藏状态
+func (gc *GuideController) fillGuideStats(c *gin.Context, responses []types.GuideResponse) {
+	if len(responses) == 0 {
+		return
+	}
+
+	guideIDs := make([]uint, 0, len(responses))
+	for _, response := range responses {
+		guideIDs = append(guideIDs, response.ID)
+	}
+
+	likeCounts := gc.countByGuide(&models.GuideLike{}, guideIDs)
+	favoriteCounts := gc.countByGuide(&models.GuideFavorite{}, guideIDs)
+	commentCounts := gc.countByGuide(&models.Comment{}, guideIDs)
+
+	var liked, favorited map[uint]bool
+	userID, exists := c.Get("user_id")
+	if exists {
+		liked = gc.guideIDsOfUser("guide_likes", userID, guideIDs)
+		favorited = gc.guideIDsOfUser("guide_favorites", userID, guideIDs)
+	}
+
+	for i := range responses {
+		id := responses[i].ID
+		responses[i].LikeCount = likeCounts[id]
+		responses[i].FavoriteCount = favoriteCounts[id]
+		responses[i].CommentCount = commentCounts[id]
+		responses[i].Liked = liked[id]
+		responses[i].Favorited = favorited[id]
+	}
+}
+
+// guideResponseWithStats 转换单个攻略并填充互动数据
+func (gc *GuideController) guideResponseWithStats(c *gin.Context, guide models.TravelGuide) types.GuideResponse {
+	responses := []types.GuideResponse{toGuideResponse(guide)}
+	gc.fillGuideStats(c, responses)
+	return responses[0]
+}
+
+// toggleGuideRelation 切换当前用户与已发布攻略之间的关系（点赞/收藏）
+// 返回切换后的状态和该攻略的总数；失败时已写入错误响应
+func (gc *GuideController) toggleGuideRelation(c *gin.Context, relation interface{}, table string) (bool, int64, bool) {
+	user, ok := currentActiveUser(gc.db, c)
+	if !ok {
+		return false, 0, false
+	}
+
+	guideID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的攻略ID"))
+		return false, 0, false
+	}
+
+	var guide models.TravelGuide
+	if err := gc.db.Scopes(publishedGuides).First(&guide, guideID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "攻略不存在"))
+		return false, 0, false
+	}
+
+	active := false
+	var count int64
+	err = gc.db.Transaction(func(tx *gorm.DB) error {
+		// 先尝试插入，已存在时忽略冲突再删除，并发的首次点赞不会违反主键
+		result := tx.Model(relation).Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{
+			"user_id":  user.ID,
+			"guide_id": guide.ID,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		active = result.RowsAffected > 0
+
+		if !active {
+			if err := tx.Where("user_id = ? AND guide_id = ?", user.ID, guide.ID).Delete(relation).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Table(table).Where("guide_id = ?", guide.ID).Count(&count).Error
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("更新攻略 %d 的 %s 失败: %v", guide.ID, table, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "操作失败"))
+		return false, 0, false
+	}
+
+	return active, count, true
+}
+
+// ToggleLike 点赞或取消点赞攻略
+func (gc *GuideController) ToggleLike(c *gin.Context) {
+	liked, count, ok := gc.toggleGuideRelation(c, &models.GuideLike{}, "guide_likes")
+	if !ok {
+		return
+	}
+
+	message := "取消点赞成功"
+	if liked {
+		message = "点赞成功"
+	}
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		gin.H{
+			"liked":      liked,
+			"like_count": count,
+		},
+		message,
+	))
+}
+
+// ToggleFavorite 收藏或取消收藏攻略
+func (gc *GuideController) ToggleFavorite(c *gin.Context) {
+	favorited, count, ok := gc.toggleGuideRelation(c, &models.GuideFavorite{}, "guide_favorites")
+	if !ok {
+		return
+	}
+
+	message := "取消收藏成功"
+	if favorited {
+		message = "收藏成功"
+	}
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		gin.H{
+			"favorited":      favorited,
+			"favorite_count": count,
+		},
+		message,
+	))
+}
+
+// GetMyFavorites 获取当前用户收藏的攻略，按收藏时间倒序
+func (gc *GuideController) GetMyFavorites(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := gc.db.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
+		Preload("User").
+		Preload("Tags").
+		Scopes(withGuideImages).
+		Joins("JOIN guide_favorites ON guide_favorites.guide_id = travel_guides.id").
+		Where("guide_favorites.user_id = ?", userID)
+
+	var total int64
+	query.Count(&total)
+
+	var guides []models.TravelGuide
+	query.Order("guide_favorites.created_at DESC").
+		Offset(offset).
+		Limit(limit + 1). // 多查询一条用于判断是否还有更多
+		Find(&guides)
+
+	hasMore := false
+	if len(guides) > limit {
+		hasMore = true
+		guides = guides[:limit]
+	}
+
+	guideResponses := make([]types.GuideResponse, 0, len(guides))
+	for _, guide := range guides {
+		guideResponses = append(guideResponses, toGuideResponse(guide))
+	}
+	gc.fillGuideStats(c, guideResponses)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.PaginationResponse{
+			List:    guideResponses,
+			Total:   total,
+			HasMore: hasMore,
+		},
+		"获取收藏成功",
+	))
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"travel_guide/models"
+)
+
+func TestToggleLike(t *testing.T) {
+	gc := &GuideController{db: newTestDB(t)}
+	author := createTestUser(t, gc.db, "alice", models.RoleUser)
+	reader := createTestUser(t, gc.db, "bob", models.RoleUser)
+
+	guide := models.TravelGuide{Title: "标题", Content: "内容", UserID: author.ID, Status: models.GuideStatusPublished, PublishedAt: time.Now()}
+	gc.db.Create(&guide)
+	target := "/api/guides/" + strconv.Itoa(int(guide.ID)) + "/like"
+
+	toggle := func() map[string]interface{} {
+		t.Helper()
+		resp := performJSON(t, http.MethodPost, "/api/guides/:id/like", target, nil, reader.ID, gc.ToggleLike)
+		if resp.Code != 0 {
+			t.Fatalf("toggle like: %s", resp.Message)
+		}
+		return resp.Data.(map[string]interface{})
+	}
+
+	if data := toggle(); data["liked"] != true || data["like_count"] != float64(1) {
+		t.Fatalf("first toggle = %v, want liked with count 1", data)
+	}
+	if data := toggle(); data["liked"] != false || data["like_count"] != float64(0) {
+		t.Fatalf("second toggle = %v, want unliked with count 0", data)
+	}
+	if data := toggle(); data["liked"] != true || data["like_count"] != float64(1) {
+		t.Fatalf("third toggle = %v, want liked with count 1", data)
+	}
+}
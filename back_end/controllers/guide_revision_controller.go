@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/diff"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// createGuideRevision 为攻略当前状态生成一条快照，guide 需已加载 Tags 和 Images
+func createGuideRevision(tx *gorm.DB, guide *models.TravelGuide, editorID uint) error {
+	imagesJSON, err := guideImagesJSON(guide.Images)
+	if err != nil {
+		return err
+	}
+
+	tagNames := make([]string, 0, len(guide.Tags))
+	for _, tag := range guide.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	tagsJSON, err := json.Marshal(tagNames)
+	if err != nil {
+		return err
+	}
+
+	// 锁住攻略行，并发保存同一攻略时依次分配版本号，避免违反 (guide_id, version) 唯一索引
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").First(&models.TravelGuide{}, guide.ID).Error; err != nil {
+		return err
+	}
+
+	var lastVersion int
+	if err := tx.Model(&models.GuideRevision{}).
+		Where("guide_id = ?", guide.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&lastVersion).Error; err != nil {
+		return err
+	}
+
+	revision := models.GuideRevision{
+		GuideID:  guide.ID,
+		Version:  lastVersion + 1,
+		Title:    guide.Title,
+		Content:  guide.Content,
+		Images:   imagesJSON,
+		Tags:     string(tagsJSON),
+		EditorID: editorID,
+	}
+	return tx.Create(&revision).Error
+}
+
+func toGuideRevisionResponse(revision models.GuideRevision) types.GuideRevisionResponse {
+	images := guideImageURLs(revision.Images)
+	tags := []string{}
+	_ = json.Unmarshal([]byte(revision.Tags), &tags)
+
+	return types.GuideRevisionResponse{
+		ID:      revision.ID,
+		GuideID: revision.GuideID,
+		Version: revision.Version,
+		Title:   revision.Title,
+		Content: revision.Content,
+		Images:  images,
+		Tags:    tags,
+		Editor: types.UserResponse{
+			ID:        revision.Editor.ID,
+			Username:  revision.Editor.Username,
+			Nickname:  revision.Editor.Nickname,
+			AvatarURL: revision.Editor.AvatarURL,
+		},
+		CreatedAt: revision.CreatedAt.Unix(),
+	}
+}
+
+// findGuideRevision 查找属于指定攻略的版本
+func (gc *GuideController) findGuideRevision(guideID uint, revisionID string) (*models.GuideRevision, error) {
+	var revision models.GuideRevision
+	if err := gc.db.Preload("Editor").
+		Where("guide_id = ?", guideID).
+		First(&revision, revisionID).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// GetGuideRevisions 获取攻略的历史版本列表（作者或可查看隐藏攻略的用户）
+func (gc *GuideController) GetGuideRevisions(c *gin.Context) {
+	guide, ok := gc.findEditableGuide(c, models.PermGuideViewHidden)
+	if !ok {
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := gc.db.Model(&models.GuideRevision{}).Where("guide_id = ?", guide.ID)
+
+	var total int64
+	query.Count(&total)
+
+	var revisions []models.GuideRevision
+	if err := query.Preload("Editor").
+		Order("version DESC").
+		Offset(offset).
+		Limit(limit + 1). // 多查询一条用于判断是否还有更多
+		Find(&revisions).Error; err != nil {
+		logger.ErrorLogger.Printf("获取攻略版本失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取攻略版本失败"))
+		return
+	}
+
+	hasMore := false
+	if len(revisions) > limit {
+		hasMore = true
+		revisions = revisions[:limit]
+	}
+
+	revisionResponses := make([]types.GuideRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionResponses = append(revisionResponses, toGuideRevisionResponse(revision))
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(
+		types.PaginationResponse{
+			List:    revisionResponses,
+			Total:   total,
+			HasMore: hasMore,
+		},
+		"获取攻略版本成功",
+	))
+}
+
+// DiffGuideRevisions 比较攻略的两个版本，参数 from 和 to 为版本ID
+func (gc *GuideController) DiffGuideRevisions(c *gin.Context) {
+	guide, ok := gc.findEditableGuide(c, models.PermGuideViewHidden)
+	if !ok {
+		return
+	}
+
+	fromID, toID := c.Query("from"), c.Query("to")
+	if fromID == "" || toID == "" {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请指定要比较的版本"))
+		return
+	}
+
+	from, err := gc.findGuideRevision(guide.ID, fromID)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "版本不存在"))
+		return
+	}
+	to, err := gc.findGuideRevision(guide.ID, toID)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "版本不存在"))
+		return
+	}
+
+	content, err := diff.Lines(from.Content, to.Content)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "两个版本的内容差异过大，无法逐行比较"))
+		return
+	}
+
+	fromResponse := toGuideRevisionResponse(*from)
+	toResponse := toGuideRevisionResponse(*to)
+
+	response := types.GuideRevisionDiffResponse{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Content:     content,
+	}
+	if from.Title != to.Title {
+		response.Title = &types.FieldChange{From: from.Title, To: to.Title}
+	}
+	response.ImagesAdded, response.ImagesRemoved = diff.Strings(fromResponse.Images, toResponse.Images)
+	response.TagsAdded, response.TagsRemoved = diff.Strings(fromResponse.Tags, toResponse.Tags)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(response, "比较版本成功"))
+}
+
+// RollbackGuideRevision 将攻略回滚到指定版本，回滚本身会生成一个新版本
+func (gc *GuideController) RollbackGuideRevision(c *gin.Context) {
+	guide, ok := gc.findEditableGuide(c, models.PermGuideEditAny)
+	if !ok {
+		return
+	}
+
+	revision, err := gc.findGuideRevision(guide.ID, c.Param("revision_id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "版本不存在"))
+		return
+	}
+
+	tags := []string{}
+	_ = json.Unmarshal([]byte(revision.Tags), &tags)
+
+	// 按快照中的顺序恢复图片，说明也恢复为当时的内容
+	userID, _ := c.Get("user_id")
+	snapshot := parseGuideImages(revision.Images)
+	urls := make([]string, 0, len(snapshot))
+	for _, image := range snapshot {
+		urls = append(urls, image.URL)
+	}
+	images, err := buildGuideImages(gc.db, guide.ID, urls, userID.(uint))
+	if err != nil {
+		logger.ErrorLogger.Printf("处理图片列表失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "回滚攻略失败"))
+		return
+	}
+	for i := range images {
+		images[i].Caption = snapshot[i].Caption
+	}
+
+	updates := map[string]interface{}{
+		"title":   revision.Title,
+		"content": revision.Content,
+	}
+	if err := gc.saveGuide(guide, updates, &tags, &images, userID.(uint)); err != nil {
+		logger.ErrorLogger.Printf("回滚攻略失败，ID %d: %v", guide.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "回滚攻略失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("攻略 %d 已回滚到版本 %d", guide.ID, revision.Version)
+	c.JSON(http.StatusOK, types.SuccessResponse(gc.guideResponseWithStats(c, *guide), "回滚攻略成功"))
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// hasPermission 判断当前请求的用户是否拥有某项权限，未登录时返回 false
+// 使用 API 密钥的请求只能操作自己的数据，不继承角色的管理权限
+func hasPermission(db *gorm.DB, c *gin.Context, permission string) bool {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return false
+	}
+	if _, viaAPIKey := c.Get("api_key_id"); viaAPIKey {
+		return false
+	}
+	return middleware.UserHasPermission(db, userID, permission)
+}
+
+// currentActiveUser 获取当前登录且未被禁用的用户
+// 失败时已写入错误响应，调用方直接返回即可
+func currentActiveUser(db *gorm.DB, c *gin.Context) (*models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return nil, false
+	}
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		logger.ErrorLogger.Printf("用户不存在: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return nil, false
+	}
+
+	if user.IsBanned(time.Now()) {
+		logger.ErrorLogger.Printf("用户 %v 已被禁用", userID)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户已被禁用"))
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// 第三方登录创建的账号没有密码，敏感操作前需要在这段时间内重新登录
+const recentLoginWindow = 10 * time.Minute
+
+// verifyIdentity 敏感操作前确认是本人：有密码的账号校验密码；
+// 没有密码的账号启用了两步验证时校验验证码或恢复码，否则要求当前会话是刚刚登录的。
+// 校验失败时返回给用户看的提示
+func verifyIdentity(db *gorm.DB, c *gin.Context, user *models.User, password, code, recoveryCode string) (string, bool) {
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return "密码错误", false
+		}
+		return "", true
+	}
+
+	if user.TwoFactorEnabled() {
+		if ok, err := verifySecondFactor(db, user, code, recoveryCode); err != nil || !ok {
+			return "验证码错误", false
+		}
+		return "", true
+	}
+
+	// API 密钥没有会话，不能用于没有密码的账号
+	sessionID, _ := c.Get("session_id")
+	var session models.UserSession
+	if id, _ := sessionID.(uint); id == 0 || db.Where("id = ? AND user_id = ?", id, user.ID).First(&session).Error != nil {
+		return "请重新登录后再操作", false
+	}
+	if time.Since(session.CreatedAt) > recentLoginWindow {
+		return "请重新通过第三方账号登录后再操作", false
+	}
+	return "", true
+}
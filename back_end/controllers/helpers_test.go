@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"travel_guide/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 测试用的表结构，模型里的 MySQL 专有定义（enum、ON UPDATE）无法在 SQLite 上自动迁移
+var testSchema = []string{
+	`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username VARCHAR(50) NOT NULL UNIQUE,
+		password VARCHAR(255) NOT NULL DEFAULT '',
+		nickname VARCHAR(100) NOT NULL,
+		avatar_url VARCHAR(255),
+		email VARCHAR(255) UNIQUE,
+		email_verified_at DATETIME,
+		phone VARCHAR(32) UNIQUE,
+		phone_verified_at DATETIME,
+		totp_secret VARCHAR(64),
+		totp_enabled_at DATETIME,
+		totp_last_step INTEGER NOT NULL DEFAULT 0,
+		role VARCHAR(50) NOT NULL DEFAULT 'user',
+		status VARCHAR(20) NOT NULL DEFAULT 'active',
+		ban_reason VARCHAR(255),
+		banned_until DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		deleted_at DATETIME
+	)`,
+	`CREATE TABLE user_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		provider VARCHAR(50) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		email VARCHAR(255),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (provider, subject)
+	)`,
+	`CREATE TABLE user_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+		previous_token_hash VARCHAR(64),
+		user_agent VARCHAR(255),
+		ip VARCHAR(64),
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE verification_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		purpose VARCHAR(20) NOT NULL,
+		target VARCHAR(255) NOT NULL,
+		token_hash VARCHAR(64) NOT NULL,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE user_recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE roles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(50) NOT NULL UNIQUE,
+		description VARCHAR(255),
+		is_system BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE role_permissions (
+		role_id INTEGER NOT NULL,
+		permission VARCHAR(100) NOT NULL,
+		PRIMARY KEY (role_id, permission)
+	)`,
+	`CREATE TABLE travel_guides (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(255) NOT NULL,
+		content TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'published',
+		published_at DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		deleted_at DATETIME
+	)`,
+	`CREATE TABLE tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(50) NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		deleted_at DATETIME
+	)`,
+	`CREATE TABLE guide_tags (
+		guide_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (guide_id, tag_id)
+	)`,
+	`CREATE TABLE guide_likes (
+		user_id INTEGER NOT NULL,
+		guide_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, guide_id)
+	)`,
+	`CREATE TABLE guide_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guide_id INTEGER NOT NULL,
+		version INTEGER NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		content TEXT NOT NULL,
+		images TEXT,
+		tags TEXT,
+		editor_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (guide_id, version)
+	)`,
+	`CREATE TABLE images (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		object_key VARCHAR(255) NOT NULL UNIQUE,
+		url VARCHAR(512) NOT NULL,
+		format VARCHAR(10) NOT NULL,
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		bytes INTEGER NOT NULL,
+		placeholder VARCHAR(7),
+		variants TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE guide_images (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guide_id INTEGER NOT NULL,
+		image_id INTEGER,
+		position INTEGER NOT NULL,
+		url VARCHAR(512) NOT NULL,
+		caption VARCHAR(500),
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		owner_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE pending_uploads (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		object_key VARCHAR(255) NOT NULL UNIQUE,
+		content_type VARCHAR(50) NOT NULL,
+		size INTEGER NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+}
+
+// newTestDB 创建只在本测试内有效的内存数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库按连接隔离，只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, stmt := range testSchema {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("create test schema: %v", err)
+		}
+	}
+	return db
+}
+
+// performJSON 调用处理函数并解析统一响应，userID 不为 0 时模拟已登录用户
+func performJSON(t *testing.T, method, route, target string, body interface{}, userID uint, handler gin.HandlerFunc) types.Response {
+	t.Helper()
+	resp, _ := performJSONWithCookies(t, method, route, target, body, userID, handler, nil)
+	return resp
+}
+
+// performJSONWithCookies 与 performJSON 相同，请求携带 cookies 并返回响应写入的 Cookie
+func performJSONWithCookies(t *testing.T, method, route, target string, body interface{}, userID uint, handler gin.HandlerFunc, cookies []*http.Cookie) (types.Response, []*http.Cookie) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+		handler(c)
+	})
+
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload = data
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp types.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return resp, w.Result().Cookies()
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"travel_guide/models"
+	"travel_guide/types"
+
+	"gorm.io/gorm"
+)
+
+// 响应中固定返回的缩略图尺寸
+var imageVariantNames = []string{"thumbnail", "medium", "large"}
+
+// toImageResponse 转换上传的图片，缺少的尺寸使用原图
+func toImageResponse(image models.Image) types.ImageResponse {
+	response := types.ImageResponse{
+		ID:          image.ID,
+		URL:         image.URL,
+		Width:       image.Width,
+		Height:      image.Height,
+		Placeholder: image.Placeholder,
+		Variants:    make(map[string]types.ImageVariantResponse, len(imageVariantNames)),
+	}
+	for _, variant := range image.VariantList() {
+		response.Variants[variant.Name] = types.ImageVariantResponse{
+			URL:    variant.URL,
+			Width:  variant.Width,
+			Height: variant.Height,
+		}
+	}
+	fillImageVariants(&response)
+	return response
+}
+
+// fillImageVariants 原图不够大时没有生成对应尺寸，用原图补齐
+func fillImageVariants(image *types.ImageResponse) {
+	if image.Variants == nil {
+		image.Variants = make(map[string]types.ImageVariantResponse, len(imageVariantNames))
+	}
+	for _, name := range imageVariantNames {
+		if _, ok := image.Variants[name]; !ok {
+			image.Variants[name] = types.ImageVariantResponse{URL: image.URL, Width: image.Width, Height: image.Height}
+		}
+	}
+}
+
+// parseGuideImages 解析版本快照中的 images 字段，兼容旧版本只保存 URL 字符串的数据
+func parseGuideImages(raw string) []types.ImageResponse {
+	var items []json.RawMessage
+	_ = json.Unmarshal([]byte(raw), &items)
+
+	images := make([]types.ImageResponse, 0, len(items))
+	for _, item := range items {
+		var image types.ImageResponse
+		var url string
+		if err := json.Unmarshal(item, &url); err == nil {
+			image.URL = url
+		} else if err := json.Unmarshal(item, &image); err != nil || image.URL == "" {
+			continue
+		}
+		fillImageVariants(&image)
+		images = append(images, image)
+	}
+	return images
+}
+
+// guideImageURLs 返回攻略图片的原图地址，用于版本对比
+func guideImageURLs(raw string) []string {
+	images := parseGuideImages(raw)
+	urls := make([]string, 0, len(images))
+	for _, image := range images {
+		urls = append(urls, image.URL)
+	}
+	return urls
+}
+
+// toGuideImageResponse 转换攻略中的图片，ID 为攻略图片的ID，ImageID 为上传图片的ID
+func toGuideImageResponse(image models.GuideImage) types.ImageResponse {
+	var response types.ImageResponse
+	if image.Image != nil {
+		response = toImageResponse(*image.Image)
+		response.ImageID = image.Image.ID
+	} else {
+		response = types.ImageResponse{URL: image.URL, Width: image.Width, Height: image.Height}
+		fillImageVariants(&response)
+	}
+	response.ID = image.ID
+	response.Caption = image.Caption
+	return response
+}
+
+func toGuideImageResponses(images []models.GuideImage) []types.ImageResponse {
+	responses := make([]types.ImageResponse, 0, len(images))
+	for _, image := range images {
+		responses = append(responses, toGuideImageResponse(image))
+	}
+	return responses
+}
+
+// withGuideImages 按顺序加载攻略图片及对应的上传图片
+func withGuideImages(db *gorm.DB) *gorm.DB {
+	return db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Preload("Images.Image")
+}
+
+// guideImagesJSON 把攻略图片序列化为版本快照中保存的 JSON
+func guideImagesJSON(images []models.GuideImage) (string, error) {
+	data, err := json.Marshal(toGuideImageResponses(images))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// buildGuideImages 把请求中的图片地址转换为攻略图片，顺序即请求中的顺序。
+// 攻略中已有的图片保留原记录和说明，通过本站上传的图片关联 images 表并以上传者为所有者，
+// 其他地址的所有者为 ownerID。guideID 为 0 表示新建攻略
+func buildGuideImages(db *gorm.DB, guideID uint, urls []string, ownerID uint) ([]models.GuideImage, error) {
+	existing := make(map[string][]models.GuideImage)
+	if guideID != 0 {
+		var records []models.GuideImage
+		if err := db.Where("guide_id = ?", guideID).Order("position, id").Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			existing[record.URL] = append(existing[record.URL], record)
+		}
+	}
+
+	uploaded := make(map[string]models.Image)
+	if len(urls) > 0 {
+		var records []models.Image
+		if err := db.Where("url IN ?", urls).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			uploaded[record.URL] = record
+		}
+	}
+
+	images := make([]models.GuideImage, 0, len(urls))
+	for _, url := range urls {
+		// 同一张图片出现多次时依次复用已有记录
+		if records := existing[url]; len(records) > 0 {
+			images = append(images, records[0])
+			existing[url] = records[1:]
+			continue
+		}
+
+		image := models.GuideImage{URL: url, OwnerID: ownerID}
+		if record, ok := uploaded[url]; ok {
+			image.ImageID = &record.ID
+			image.OwnerID = record.UserID
+			image.Width = record.Width
+			image.Height = record.Height
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// replaceGuideImages 用 images 替换攻略的图片列表，Position 按切片顺序重新编号，
+// 带 ID 的图片只更新顺序和说明，不在列表中的旧图片会被删除
+func replaceGuideImages(tx *gorm.DB, guideID uint, images []models.GuideImage) error {
+	keep := make([]uint, 0, len(images))
+	for _, image := range images {
+		if image.ID != 0 {
+			keep = append(keep, image.ID)
+		}
+	}
+
+	query := tx.Where("guide_id = ?", guideID)
+	if len(keep) > 0 {
+		query = query.Where("id NOT IN ?", keep)
+	}
+	if err := query.Delete(&models.GuideImage{}).Error; err != nil {
+		return err
+	}
+
+	for position, image := range images {
+		if image.ID != 0 {
+			err := tx.Model(&models.GuideImage{}).
+				Where("id = ? AND guide_id = ?", image.ID, guideID).
+				Updates(map[string]interface{}{"position": position, "caption": image.Caption}).Error
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		image.GuideID = guideID
+		image.Position = position
+		image.Image = nil
+		if err := tx.Create(&image).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
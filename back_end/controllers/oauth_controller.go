@@ -0,0 +1,377 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"travel_guide/config"
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/avatar"
+	"travel_guide/utils/logger"
+	"travel_guide/utils/oauth"
+	"travel_guide/utils/validate"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 授权请求的有效期
+const oauthStateTTL = 10 * time.Minute
+
+// 把授权请求绑定到发起它的浏览器的 Cookie，防止把别人发起的授权地址发给受害者完成登录或绑定
+const (
+	oauthBindingCookie = "oauth_binding"
+	oauthCookiePath    = "/api/oauth"
+)
+
+type OAuthController struct {
+	db        *gorm.DB
+	providers *oauth.Registry
+	states    oauth.StateStore
+}
+
+// NewOAuthController 按配置注册 OpenID Connect 提供方
+func NewOAuthController(db *gorm.DB) *OAuthController {
+	registry := oauth.NewRegistry()
+	for _, p := range config.AppConfig.OAuthConfig.Providers {
+		registry.Register(oauth.NewOIDCProvider(oauth.OIDCConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}))
+	}
+	return &OAuthController{
+		db:        db,
+		providers: registry,
+		states:    oauth.NewMemoryStateStore(),
+	}
+}
+
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+type IdentityResponse struct {
+	Provider  string `json:"provider"`
+	Email     string `json:"email"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+var errIdentityLinked = errors.New("该第三方账号已绑定其他用户")
+
+// GetOAuthProviders 列出已启用的第三方登录方式
+func (oc *OAuthController) GetOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, types.SuccessResponse(oc.providers.Names(), "获取登录方式成功"))
+}
+
+// Authorize 生成授权地址，前端跳转到该地址完成登录；带 link=true 时为当前登录用户绑定外部身份
+func (oc *OAuthController) Authorize(c *gin.Context) {
+	provider, err := oc.providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不支持的登录方式"))
+		return
+	}
+
+	pending := oauth.PendingLogin{
+		Provider:  provider.Name(),
+		ExpiresAt: time.Now().Add(oauthStateTTL),
+	}
+	if c.Query("link") == "true" {
+		user, ok := currentActiveUser(oc.db, c)
+		if !ok {
+			return
+		}
+		pending.LinkUserID = user.ID
+	}
+
+	state, err := oauth.RandomString(24)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成授权请求失败"))
+		return
+	}
+	verifier, challenge, err := oauth.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成授权请求失败"))
+		return
+	}
+	pending.CodeVerifier = verifier
+	binding, err := oauth.RandomString(24)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成授权请求失败"))
+		return
+	}
+	pending.BindingHash = middleware.HashToken(binding)
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, challenge)
+	if err != nil {
+		logger.ErrorLogger.Printf("生成 %s 授权地址失败: %v", provider.Name(), err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "第三方登录服务暂不可用"))
+		return
+	}
+	oc.states.Save(state, pending)
+	setOAuthBindingCookie(c, binding, int(oauthStateTTL.Seconds()))
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{
+		"authorize_url": authURL,
+		"state":         state,
+	}, "获取授权地址成功"))
+}
+
+// Callback 前端回调页面拿到 code 和 state 后调用，完成登录、自动注册或绑定
+func (oc *OAuthController) Callback(c *gin.Context) {
+	var req OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	binding, _ := c.Cookie(oauthBindingCookie)
+	setOAuthBindingCookie(c, "", -1)
+
+	pending, ok := oc.states.Take(req.State)
+	if !ok || pending.Provider != c.Param("provider") ||
+		binding == "" || subtle.ConstantTimeCompare([]byte(middleware.HashToken(binding)), []byte(pending.BindingHash)) != 1 {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "授权请求无效或已过期，请重新登录"))
+		return
+	}
+
+	// 绑定只能由发起绑定的用户本人完成
+	if pending.LinkUserID != 0 {
+		userID, _ := c.Get("user_id")
+		if id, _ := userID.(uint); id != pending.LinkUserID {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "请使用发起绑定的账号完成绑定"))
+			return
+		}
+	}
+
+	provider, err := oc.providers.Get(pending.Provider)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不支持的登录方式"))
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), req.Code, pending.CodeVerifier)
+	if err != nil {
+		logger.ErrorLogger.Printf("%s 授权码换取失败: %v", provider.Name(), err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "第三方登录失败"))
+		return
+	}
+
+	if pending.LinkUserID != 0 {
+		oc.linkIdentity(c, pending.LinkUserID, provider.Name(), identity)
+		return
+	}
+
+	user, err := oc.findOrProvisionUser(provider.Name(), identity)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "该第三方账号绑定的用户已注销"))
+			return
+		}
+		logger.ErrorLogger.Printf("%s 登录创建用户失败: %v", provider.Name(), err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "第三方登录失败"))
+		return
+	}
+
+	completeLogin(oc.db, c, *user)
+}
+
+// setOAuthBindingCookie 写入或清除（maxAge < 0）授权请求绑定的 Cookie
+func setOAuthBindingCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthBindingCookie, value, maxAge, oauthCookiePath, "", c.Request.TLS != nil, true)
+}
+
+// findOrProvisionUser 查找外部身份绑定的用户，首次登录时用提供方的昵称和头像创建账号
+func (oc *OAuthController) findOrProvisionUser(provider string, identity *oauth.Identity) (*models.User, error) {
+	var existing models.UserIdentity
+	err := oc.db.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&existing).Error
+	if err == nil {
+		var user models.User
+		if err := oc.db.First(&user, existing.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	username, err := oc.generateUsername(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	nickname := strings.TrimSpace(identity.Nickname)
+	if nickname == "" || validate.Nickname(nickname) != "" {
+		nickname = username
+	}
+	avatarURL := identity.AvatarURL
+	if avatarURL == "" || len(avatarURL) > 255 {
+		avatarURL = avatar.Default(nickname)
+	}
+
+	// 自动创建的账号没有密码，只能通过第三方登录，设置密码后才能使用密码登录
+	user := models.User{
+		Username:  username,
+		Nickname:  nickname,
+		AvatarURL: avatarURL,
+		Role:      models.RoleUser,
+		Status:    models.StatusActive,
+	}
+	err = oc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("通过 %s 登录自动创建用户 %s", provider, username)
+	return &user, nil
+}
+
+var usernameUnsafeChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+// generateUsername 生成 <provider>_<随机串> 形式且未被占用的用户名
+func (oc *OAuthController) generateUsername(provider string) (string, error) {
+	prefix := usernameUnsafeChars.ReplaceAllString(strings.ToLower(provider), "_")
+	if prefix == "" || prefix[0] < 'a' || prefix[0] > 'z' {
+		prefix = "u" + prefix
+	}
+	if len(prefix) > 20 {
+		prefix = prefix[:20]
+	}
+
+	for i := 0; i < 5; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		username := prefix + "_" + hex.EncodeToString(b)
+
+		var count int64
+		oc.db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count)
+		if count == 0 {
+			return username, nil
+		}
+	}
+	return "", errors.New("failed to generate unique username")
+}
+
+// linkIdentity 为已登录用户绑定外部身份
+func (oc *OAuthController) linkIdentity(c *gin.Context, userID uint, provider string, identity *oauth.Identity) {
+	err := oc.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&existing).Error
+		if err == nil {
+			if existing.UserID != userID {
+				return errIdentityLinked
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// 每种登录方式只绑定一个账号，重新绑定时替换旧的
+		if err := tx.Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserIdentity{
+			UserID:   userID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if errors.Is(err, errIdentityLinked) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Printf("用户 %d 绑定 %s 失败: %v", userID, provider, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "绑定失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"provider": provider}, "绑定成功"))
+}
+
+// GetMyIdentities 列出当前用户绑定的第三方账号
+func (oc *OAuthController) GetMyIdentities(c *gin.Context) {
+	user, ok := currentActiveUser(oc.db, c)
+	if !ok {
+		return
+	}
+
+	var identities []models.UserIdentity
+	if err := oc.db.Where("user_id = ?", user.ID).Order("id ASC").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取绑定信息失败"))
+		return
+	}
+
+	response := make([]IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, IdentityResponse{
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt.Unix(),
+		})
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(response, "获取绑定信息成功"))
+}
+
+// UnlinkIdentity 解绑第三方账号，没有密码的账号不能解绑最后一个登录方式
+func (oc *OAuthController) UnlinkIdentity(c *gin.Context) {
+	user, ok := currentActiveUser(oc.db, c)
+	if !ok {
+		return
+	}
+
+	provider := c.Param("provider")
+	var identities []models.UserIdentity
+	oc.db.Where("user_id = ?", user.ID).Find(&identities)
+
+	found := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			found = true
+		}
+	}
+	if !found {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未绑定该登录方式"))
+		return
+	}
+	if user.Password == "" && len(identities) == 1 {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请先设置密码再解绑"))
+		return
+	}
+
+	if err := oc.db.Where("user_id = ? AND provider = ?", user.ID, provider).Delete(&models.UserIdentity{}).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "解绑失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "解绑成功"))
+}
@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/utils/oauth"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oidcStub 本地的 OpenID Connect 提供方，换取令牌时按授权时的 code_challenge 校验 code_verifier
+type oidcStub struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	challenges map[string]string
+	subject    string
+}
+
+func newOIDCStub(t *testing.T) *oidcStub {
+	t.Helper()
+	s := &oidcStub{challenges: make(map[string]string), subject: "stub-subject"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"userinfo_endpoint":      s.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		s.mu.Lock()
+		challenge, ok := s.challenges[r.PostForm.Get("code")]
+		delete(s.challenges, r.PostForm.Get("code"))
+		s.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		subject := s.subject
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"sub": subject, "name": "Stub User", "email": "stub@example.com"})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// approve 模拟用户在提供方同意授权，返回回调中的 code
+func (s *oidcStub) approve(t *testing.T, authorizeURL string) string {
+	t.Helper()
+	u, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatalf("parse authorize url: %v", err)
+	}
+	code, err := oauth.RandomString(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.challenges[code] = u.Query().Get("code_challenge")
+	s.mu.Unlock()
+	return code
+}
+
+func (s *oidcStub) setSubject(subject string) {
+	s.mu.Lock()
+	s.subject = subject
+	s.mu.Unlock()
+}
+
+func newTestOAuthController(t *testing.T) (*OAuthController, *oidcStub) {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+
+	stub := newOIDCStub(t)
+	registry := oauth.NewRegistry()
+	registry.Register(oauth.NewOIDCProvider(oauth.OIDCConfig{
+		Name:        "stub",
+		Issuer:      stub.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/oauth/callback",
+	}))
+	return &OAuthController{
+		db:        newTestDB(t),
+		providers: registry,
+		states:    oauth.NewMemoryStateStore(),
+	}, stub
+}
+
+// oauthAttempt 一次授权流程回调时需要的参数：回调请求、发起授权的浏览器 Cookie 和回调时的登录用户
+type oauthAttempt struct {
+	req     OAuthCallbackRequest
+	cookies []*http.Cookie
+	userID  uint
+}
+
+// authorize 发起授权并在提供方同意，回调默认由发起授权的同一用户在同一浏览器中完成
+func authorize(t *testing.T, oc *OAuthController, stub *oidcStub, userID uint, link bool) oauthAttempt {
+	t.Helper()
+	target := "/api/oauth/stub/authorize"
+	if link {
+		target += "?link=true"
+	}
+	resp, cookies := performJSONWithCookies(t, http.MethodGet, "/api/oauth/:provider/authorize", target, nil, userID, oc.Authorize, nil)
+	if resp.Code != 0 {
+		t.Fatalf("authorize: %s", resp.Message)
+	}
+	data := resp.Data.(map[string]interface{})
+	return oauthAttempt{
+		req: OAuthCallbackRequest{
+			Code:  stub.approve(t, data["authorize_url"].(string)),
+			State: data["state"].(string),
+		},
+		cookies: cookies,
+		userID:  userID,
+	}
+}
+
+func callback(t *testing.T, oc *OAuthController, attempt oauthAttempt) (int, string, map[string]interface{}) {
+	t.Helper()
+	resp, _ := performJSONWithCookies(t, http.MethodPost, "/api/oauth/:provider/callback", "/api/oauth/stub/callback", attempt.req, attempt.userID, oc.Callback, attempt.cookies)
+	data, _ := resp.Data.(map[string]interface{})
+	return resp.Code, resp.Message, data
+}
+
+func TestOAuthCallbackProvisionsUser(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	code, msg, data := callback(t, oc, authorize(t, oc, stub, 0, false))
+	if code != 0 {
+		t.Fatalf("callback: %s", msg)
+	}
+	if token, _ := data["token"].(string); token == "" {
+		t.Fatalf("callback did not issue tokens: %v", data)
+	}
+
+	var identity models.UserIdentity
+	if err := oc.db.Where("provider = ? AND subject = ?", "stub", "stub-subject").First(&identity).Error; err != nil {
+		t.Fatalf("identity not created: %v", err)
+	}
+	var user models.User
+	if err := oc.db.First(&user, identity.UserID).Error; err != nil {
+		t.Fatalf("user not created: %v", err)
+	}
+	if user.Password != "" || user.Nickname != "Stub User" {
+		t.Fatalf("provisioned user = %+v", user)
+	}
+
+	// 再次登录使用已创建的账号
+	code, msg, _ = callback(t, oc, authorize(t, oc, stub, 0, false))
+	if code != 0 {
+		t.Fatalf("second callback: %s", msg)
+	}
+	var count int64
+	oc.db.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("users = %d, want 1", count)
+	}
+}
+
+func TestOAuthCallbackStateSingleUse(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	attempt := authorize(t, oc, stub, 0, false)
+	if code, msg, _ := callback(t, oc, attempt); code != 0 {
+		t.Fatalf("callback: %s", msg)
+	}
+
+	// 同一个 state 再次回调，即使提供方仍接受授权码也必须拒绝
+	attempt.req.Code = stub.approve(t, "http://stub/authorize?code_challenge=x")
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("state was accepted twice")
+	}
+}
+
+func TestOAuthCallbackExpiredState(t *testing.T) {
+	oc, _ := newTestOAuthController(t)
+
+	oc.states.Save("expired", oauth.PendingLogin{
+		Provider:     "stub",
+		CodeVerifier: "verifier",
+		BindingHash:  middleware.HashToken("binding"),
+		ExpiresAt:    time.Now().Add(-time.Second),
+	})
+	attempt := oauthAttempt{
+		req:     OAuthCallbackRequest{Code: "code", State: "expired"},
+		cookies: []*http.Cookie{{Name: oauthBindingCookie, Value: "binding"}},
+	}
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("expired state was accepted")
+	}
+}
+
+func TestOAuthCallbackRejectsWrongVerifier(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	attempt := authorize(t, oc, stub, 0, false)
+	pending, _ := oc.states.Take(attempt.req.State)
+	pending.CodeVerifier = "not-the-verifier"
+	oc.states.Save(attempt.req.State, pending)
+
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("callback succeeded with a mismatched code_verifier")
+	}
+	var count int64
+	oc.db.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("users = %d, want 0", count)
+	}
+}
+
+func TestOAuthLinkIdentity(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Passw0rd!"), bcrypt.MinCost)
+	alice := models.User{Username: "alice", Password: string(hash), Nickname: "alice", Role: models.RoleUser, Status: models.StatusActive}
+	bob := models.User{Username: "bob", Password: string(hash), Nickname: "bob", Role: models.RoleUser, Status: models.StatusActive}
+	oc.db.Create(&alice)
+	oc.db.Create(&bob)
+
+	code, msg, _ := callback(t, oc, authorize(t, oc, stub, alice.ID, true))
+	if code != 0 {
+		t.Fatalf("link: %s", msg)
+	}
+	var identity models.UserIdentity
+	if err := oc.db.Where("provider = ? AND subject = ?", "stub", "stub-subject").First(&identity).Error; err != nil {
+		t.Fatalf("identity not linked: %v", err)
+	}
+	if identity.UserID != alice.ID {
+		t.Fatalf("identity linked to user %d, want %d", identity.UserID, alice.ID)
+	}
+
+	// 同一个外部身份不能再绑定到其他用户
+	code, msg, _ = callback(t, oc, authorize(t, oc, stub, bob.ID, true))
+	if code == 0 || msg != errIdentityLinked.Error() {
+		t.Fatalf("linking to another user: code %d, message %q", code, msg)
+	}
+
+	// 重新绑定同一提供方的其他身份时替换旧的绑定
+	stub.setSubject("another-subject")
+	if code, msg, _ = callback(t, oc, authorize(t, oc, stub, alice.ID, true)); code != 0 {
+		t.Fatalf("relink: %s", msg)
+	}
+	var identities []models.UserIdentity
+	oc.db.Where("user_id = ?", alice.ID).Find(&identities)
+	if len(identities) != 1 || identities[0].Subject != "another-subject" {
+		t.Fatalf("identities after relink = %+v", identities)
+	}
+}
+
+func TestOAuthUnlinkLastIdentity(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	if code, msg, _ := callback(t, oc, authorize(t, oc, stub, 0, false)); code != 0 {
+		t.Fatalf("callback: %s", msg)
+	}
+	var identity models.UserIdentity
+	oc.db.First(&identity)
+
+	unlink := func() (int, string) {
+		resp := performJSON(t, http.MethodDelete, "/api/me/identities/:provider", "/api/me/identities/stub", nil, identity.UserID, oc.UnlinkIdentity)
+		return resp.Code, resp.Message
+	}
+
+	// 没有密码的账号不能解绑唯一的登录方式
+	if code, msg := unlink(); code == 0 || msg != "请先设置密码再解绑" {
+		t.Fatalf("unlink without password: code %d, message %q", code, msg)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Passw0rd!"), bcrypt.MinCost)
+	oc.db.Model(&models.User{}).Where("id = ?", identity.UserID).Update("password", string(hash))
+	if code, msg := unlink(); code != 0 {
+		t.Fatalf("unlink with password: %s", msg)
+	}
+	var count int64
+	oc.db.Model(&models.UserIdentity{}).Where("user_id = ?", identity.UserID).Count(&count)
+	if count != 0 {
+		t.Fatalf("identities = %d, want 0", count)
+	}
+}
+
+func TestOAuthCallbackRequiresSameBrowser(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	// 攻击者发起授权后把回调地址发给受害者，受害者的浏览器没有对应的 Cookie
+	attempt := authorize(t, oc, stub, 0, false)
+	if len(attempt.cookies) != 1 || attempt.cookies[0].Name != oauthBindingCookie || !attempt.cookies[0].HttpOnly {
+		t.Fatalf("authorize cookies = %+v, want one HttpOnly %s cookie", attempt.cookies, oauthBindingCookie)
+	}
+	attempt.cookies = nil
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("callback without the binding cookie succeeded")
+	}
+
+	attempt = authorize(t, oc, stub, 0, false)
+	attempt.cookies = authorize(t, oc, stub, 0, false).cookies
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("callback with another flow's binding cookie succeeded")
+	}
+
+	var count int64
+	oc.db.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("users = %d, want 0", count)
+	}
+}
+
+func TestOAuthLinkRequiresInitiatingUser(t *testing.T) {
+	oc, stub := newTestOAuthController(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Passw0rd!"), bcrypt.MinCost)
+	attacker := models.User{Username: "mallory", Password: string(hash), Nickname: "mallory", Role: models.RoleUser, Status: models.StatusActive}
+	victim := models.User{Username: "alice", Password: string(hash), Nickname: "alice", Role: models.RoleUser, Status: models.StatusActive}
+	oc.db.Create(&attacker)
+	oc.db.Create(&victim)
+
+	// 攻击者为自己的账号发起绑定，回调由受害者完成
+	attempt := authorize(t, oc, stub, attacker.ID, true)
+	attempt.userID = victim.ID
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("link completed by a different user")
+	}
+
+	attempt = authorize(t, oc, stub, attacker.ID, true)
+	attempt.userID = 0
+	if code, _, _ := callback(t, oc, attempt); code == 0 {
+		t.Fatal("link completed without a logged-in user")
+	}
+
+	var count int64
+	oc.db.Model(&models.UserIdentity{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("identities = %d, want 0", count)
+	}
+}
@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoleController struct {
+	db *gorm.DB
+}
+
+func NewRoleController(db *gorm.DB) *RoleController {
+	return &RoleController{db: db}
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest 修改角色，未传的字段保持不变
+type UpdateRoleRequest struct {
+	Description *string   `json:"description" binding:"omitempty,max=255"`
+	Permissions *[]string `json:"permissions"`
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// validatePermissions 检查权限名称是否都是系统支持的权限，并去重排序
+func validatePermissions(permissions []string) ([]string, bool) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if !models.IsKnownPermission(p) {
+			return nil, false
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	sort.Strings(result)
+	return result, true
+}
+
+func (rc *RoleController) toRoleResponse(role models.Role) types.RoleResponse {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		permissions = append(permissions, p.Permission)
+	}
+	sort.Strings(permissions)
+
+	response := types.RoleResponse{
+		ID:          role.ID,
+		Name:        string(role.Name),
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+		Permissions: permissions,
+	}
+	rc.db.Model(&models.User{}).Where("role = ?", role.Name).Count(&response.UserCount)
+	return response
+}
+
+// findRole 查找路径参数中的角色，失败时已写入错误响应
+func (rc *RoleController) findRole(c *gin.Context) (*models.Role, bool) {
+	var role models.Role
+	if err := rc.db.Preload("Permissions").Where("name = ?", c.Param("name")).First(&role).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "角色不存在"))
+		return nil, false
+	}
+	return &role, true
+}
+
+// replacePermissions 在事务中重写角色的权限
+func replacePermissions(tx *gorm.DB, roleID uint, permissions []string) error {
+	if err := tx.Where("role_id = ?", roleID).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	rows := make([]models.RolePermission, 0, len(permissions))
+	for _, p := range permissions {
+		rows = append(rows, models.RolePermission{RoleID: roleID, Permission: p})
+	}
+	return tx.Create(&rows).Error
+}
+
+// GetPermissions 列出系统支持的全部权限
+func (rc *RoleController) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, types.SuccessResponse(models.Permissions, "获取权限列表成功"))
+}
+
+// GetRoles 列出全部角色及其权限
+func (rc *RoleController) GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := rc.db.Preload("Permissions").Order("id ASC").Find(&roles).Error; err != nil {
+		logger.ErrorLogger.Printf("获取角色列表失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取角色列表失败"))
+		return
+	}
+
+	response := make([]types.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, rc.toRoleResponse(role))
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(response, "获取角色列表成功"))
+}
+
+// CreateRole 创建自定义角色
+func (rc *RoleController) CreateRole(c *gin.Context) {
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	if !roleNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "角色名只能包含小写字母、数字和下划线，且以字母开头"))
+		return
+	}
+
+	permissions, ok := validatePermissions(req.Permissions)
+	if !ok {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "包含未知的权限"))
+		return
+	}
+
+	// 与分配角色相同，只能授予自己拥有的权限
+	operator, ok := currentActiveUser(rc.db, c)
+	if !ok {
+		return
+	}
+	if !middleware.RoleCoversPermissions(rc.db, middleware.EffectiveRole(*operator), permissions) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不能授予自己没有的权限"))
+		return
+	}
+
+	var count int64
+	rc.db.Model(&models.Role{}).Where("name = ?", req.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "角色已存在"))
+		return
+	}
+
+	role := models.Role{
+		Name:        models.UserRole(req.Name),
+		Description: req.Description,
+	}
+	err := rc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Create(&role).Error; err != nil {
+			return err
+		}
+		return replacePermissions(tx, role.ID, permissions)
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("创建角色失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "创建角色失败"))
+		return
+	}
+
+	middleware.InvalidateRolePermissions()
+	rc.db.Preload("Permissions").First(&role, role.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(rc.toRoleResponse(role), "创建角色成功"))
+}
+
+// UpdateRole 修改角色描述或权限，管理员角色的权限不可修改
+func (rc *RoleController) UpdateRole(c *gin.Context) {
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	role, ok := rc.findRole(c)
+	if !ok {
+		return
+	}
+
+	// 不能修改自己的角色，也不能修改权限高于自己的角色
+	operator, ok := currentActiveUser(rc.db, c)
+	if !ok {
+		return
+	}
+	operatorRole := middleware.EffectiveRole(*operator)
+	if role.Name == operator.Role {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不能修改自己所属的角色"))
+		return
+	}
+	if !middleware.RoleCovers(rc.db, operatorRole, role.Name) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无权修改该角色"))
+		return
+	}
+
+	var permissions []string
+	if req.Permissions != nil {
+		if role.Name == models.RoleAdmin {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "管理员角色的权限不可修改"))
+			return
+		}
+		if permissions, ok = validatePermissions(*req.Permissions); !ok {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "包含未知的权限"))
+			return
+		}
+		if !middleware.RoleCoversPermissions(rc.db, operatorRole, permissions) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "不能授予自己没有的权限"))
+			return
+		}
+	}
+
+	err := rc.db.Transaction(func(tx *gorm.DB) error {
+		if req.Description != nil {
+			if err := tx.Model(role).Update("description", *req.Description).Error; err != nil {
+				return err
+			}
+		}
+		if req.Permissions != nil {
+			return replacePermissions(tx, role.ID, permissions)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("修改角色 %s 失败: %v", role.Name, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "修改角色失败"))
+		return
+	}
+
+	middleware.InvalidateRolePermissions()
+	rc.db.Preload("Permissions").First(role, role.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(rc.toRoleResponse(*role), "修改角色成功"))
+}
+
+// DeleteRole 删除自定义角色，仍有用户使用的角色不能删除
+func (rc *RoleController) DeleteRole(c *gin.Context) {
+	role, ok := rc.findRole(c)
+	if !ok {
+		return
+	}
+
+	if role.IsSystem {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "内置角色不能删除"))
+		return
+	}
+
+	var count int64
+	rc.db.Unscoped().Model(&models.User{}).Where("role = ?", role.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "仍有用户使用该角色，无法删除"))
+		return
+	}
+
+	err := rc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("删除角色 %s 失败: %v", role.Name, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "删除角色失败"))
+		return
+	}
+
+	middleware.InvalidateRolePermissions()
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "删除角色成功"))
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"travel_guide/middleware"
+	"travel_guide/models"
+)
+
+// createTestRole 创建角色并清空权限缓存
+func createTestRole(t *testing.T, rc *RoleController, name models.UserRole, permissions ...string) {
+	t.Helper()
+	role := models.Role{Name: name}
+	if err := rc.db.Omit("Permissions").Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	if err := replacePermissions(rc.db, role.ID, permissions); err != nil {
+		t.Fatalf("set permissions: %v", err)
+	}
+	middleware.InvalidateRolePermissions()
+}
+
+func TestRoleManagementCannotEscalate(t *testing.T) {
+	rc := &RoleController{db: newTestDB(t)}
+	createTestRole(t, rc, "manager", models.PermRoleManage, models.PermUserView)
+	createTestRole(t, rc, "viewer", models.PermUserView)
+	createTestRole(t, rc, models.RoleAdmin, models.PermissionAll)
+	operator := createTestUser(t, rc.db, "manager", "manager")
+	t.Cleanup(middleware.InvalidateRolePermissions)
+
+	create := func(req CreateRoleRequest) (int, string) {
+		resp := performJSON(t, http.MethodPost, "/api/roles", "/api/roles", req, operator.ID, rc.CreateRole)
+		return resp.Code, resp.Message
+	}
+	update := func(name string, req UpdateRoleRequest) (int, string) {
+		resp := performJSON(t, http.MethodPut, "/api/roles/:name", "/api/roles/"+name, req, operator.ID, rc.UpdateRole)
+		return resp.Code, resp.Message
+	}
+
+	if code, _ := create(CreateRoleRequest{Name: "editor", Permissions: []string{models.PermGuideEditAny}}); code == 0 {
+		t.Fatal("created a role with a permission the operator does not have")
+	}
+	if code, msg := create(CreateRoleRequest{Name: "helper", Permissions: []string{models.PermUserView}}); code != 0 {
+		t.Fatalf("create role within the operator's permissions: %s", msg)
+	}
+
+	escalate := []string{models.PermRoleManage, models.PermUserView, models.PermUserRoleAssign}
+	if code, _ := update("manager", UpdateRoleRequest{Permissions: &escalate}); code == 0 {
+		t.Fatal("operator changed the permissions of their own role")
+	}
+	description := "改个描述"
+	if code, _ := update("manager", UpdateRoleRequest{Description: &description}); code == 0 {
+		t.Fatal("operator edited their own role")
+	}
+	if code, _ := update("viewer", UpdateRoleRequest{Permissions: &escalate}); code == 0 {
+		t.Fatal("granted a permission the operator does not have to another role")
+	}
+	if code, _ := update(string(models.RoleAdmin), UpdateRoleRequest{Description: &description}); code == 0 {
+		t.Fatal("operator edited a role with more permissions than their own")
+	}
+
+	reduced := []string{}
+	if code, msg := update("viewer", UpdateRoleRequest{Permissions: &reduced}); code != 0 {
+		t.Fatalf("update another role within the operator's permissions: %s", msg)
+	}
+	if middleware.RoleHasPermission(rc.db, "manager", models.PermUserRoleAssign) {
+		t.Fatal("operator's role gained user.role.assign")
+	}
+}
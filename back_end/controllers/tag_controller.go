@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
 	"travel_guide/models"
 	"travel_guide/types"
@@ -30,8 +31,14 @@ func NewTagController(db *gorm.DB) *TagController {
 }
 
 func (tc *TagController) GetAllTags(c *gin.Context) {
+	query := tc.db
+	// 有标签管理权限的用户可以查看已删除的标签
+	if c.Query("include_deleted") == "true" && hasPermission(tc.db, c, models.PermTagDelete) {
+		query = query.Unscoped()
+	}
+
 	var tags []models.Tag
-	if err := tc.db.Find(&tags).Error; err != nil {
+	if err := query.Find(&tags).Error; err != nil {
 		logger.ErrorLogger.Printf("获取标签失败: %v", err)
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取标签失败"))
 		return
@@ -39,15 +46,130 @@ func (tc *TagController) GetAllTags(c *gin.Context) {
 
 	var response []types.TagResponse
 	for _, tag := range tags {
-		response = append(response, types.TagResponse{
+		tagResponse := types.TagResponse{
 			ID:   tag.ID,
 			Name: tag.Name,
-		})
+		}
+		if tag.DeletedAt.Valid {
+			tagResponse.DeletedAt = tag.DeletedAt.Time.Unix()
+		}
+		response = append(response, tagResponse)
 	}
 
 	c.JSON(http.StatusOK, types.SuccessResponse(response, "获取标签成功"))
 }
 
+// DeleteTag 软删除标签（管理员）
+func (tc *TagController) DeleteTag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的标签ID"))
+		return
+	}
+
+	var tag models.Tag
+	if err := tc.db.First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "标签不存在"))
+		return
+	}
+
+	if err := tc.db.Delete(&tag).Error; err != nil {
+		logger.ErrorLogger.Printf("删除标签失败，ID %d: %v", id, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "删除标签失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": tag.ID}, "删除标签成功"))
+}
+
+// RestoreTag 恢复已删除的标签（管理员）
+func (tc *TagController) RestoreTag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的标签ID"))
+		return
+	}
+
+	var tag models.Tag
+	if err := tc.db.Unscoped().First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "标签不存在"))
+		return
+	}
+
+	if !tag.DeletedAt.Valid {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "标签未被删除"))
+		return
+	}
+
+	if err := tc.db.Unscoped().Model(&tag).Update("deleted_at", nil).Error; err != nil {
+		logger.ErrorLogger.Printf("恢复标签失败，ID %d: %v", id, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "恢复标签失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": tag.ID}, "恢复标签成功"))
+}
+
+type MergeTagRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
+// MergeTag 将标签合并到目标标签：攻略和用户偏好改为关联目标标签，原标签被软删除
+func (tc *TagController) MergeTag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的标签ID"))
+		return
+	}
+
+	var req MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	if uint(id) == req.TargetID {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不能合并到自身"))
+		return
+	}
+
+	var source, target models.Tag
+	if err := tc.db.First(&source, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "标签不存在"))
+		return
+	}
+	if err := tc.db.First(&target, req.TargetID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "目标标签不存在"))
+		return
+	}
+
+	err = tc.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"guide_tags", "user_tags"} {
+			owner := "guide_id"
+			if table == "user_tags" {
+				owner = "user_id"
+			}
+			// 已同时关联两个标签的记录由 INSERT IGNORE 去重
+			if err := tx.Exec("INSERT IGNORE INTO "+table+" ("+owner+", tag_id) SELECT "+owner+", ? FROM "+table+" WHERE tag_id = ?",
+				target.ID, source.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM "+table+" WHERE tag_id = ?", source.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&source).Error
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("合并标签失败，%d -> %d: %v", source.ID, target.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "合并标签失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("标签 %d 已合并到 %d", source.ID, target.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(types.TagResponse{ID: target.ID, Name: target.Name}, "合并标签成功"))
+}
+
 // GetRelatedTags 获取搜索词相关的标签
 func (tc *TagController) GetRelatedTags(c *gin.Context) {
 	keyword := c.Query("keyword")
@@ -70,6 +192,7 @@ func (tc *TagController) GetRelatedTags(c *gin.Context) {
 	tc.db.Model(&models.Tag{}).
 		Joins("JOIN guide_tags ON guide_tags.tag_id = tags.id").
 		Joins("JOIN travel_guides ON travel_guides.id = guide_tags.guide_id").
+		Where("travel_guides.deleted_at IS NULL AND travel_guides.status = ?", models.GuideStatusPublished).
 		Where("travel_guides.title LIKE ? OR travel_guides.content LIKE ?", "%"+keyword+"%", "%"+keyword+"%").
 		Group("tags.id").
 		Count(&totalCount)
@@ -78,6 +201,7 @@ func (tc *TagController) GetRelatedTags(c *gin.Context) {
 	tc.db.Model(&models.Tag{}).
 		Joins("JOIN guide_tags ON guide_tags.tag_id = tags.id").
 		Joins("JOIN travel_guides ON travel_guides.id = guide_tags.guide_id").
+		Where("travel_guides.deleted_at IS NULL AND travel_guides.status = ?", models.GuideStatusPublished).
 		Where("travel_guides.title LIKE ? OR travel_guides.content LIKE ?", "%"+keyword+"%", "%"+keyword+"%").
 		Group("tags.id").
 		Order("COUNT(*) DESC").
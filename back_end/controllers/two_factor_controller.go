@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"travel_guide/config"
+	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/types"
+	"travel_guide/utils/logger"
+	"travel_guide/utils/totp"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// 密码校验通过后完成两步验证的时限
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+type TwoFactorController struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorController(db *gorm.DB) *TwoFactorController {
+	return &TwoFactorController{db: db}
+}
+
+type EnableTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest 关闭两步验证需要密码（已设置时）和验证码或恢复码
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// normalizeRecoveryCode 忽略大小写、空格和连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// verifySecondFactor 校验验证码或恢复码，验证码的时间步和恢复码都只能使用一次
+func verifySecondFactor(db *gorm.DB, user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), 1)
+		if !ok {
+			return false, nil
+		}
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		return result.RowsAffected == 1, nil
+	}
+
+	if recoveryCode != "" {
+		result := db.Model(&models.UserRecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, middleware.HashToken(normalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			logger.InfoLogger.Printf("用户 %d 使用恢复码完成两步验证", user.ID)
+		}
+		return result.RowsAffected == 1, nil
+	}
+
+	return false, nil
+}
+
+// replaceRecoveryCodes 生成一组新的恢复码，旧的全部作废，返回明文供用户保存
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.UserRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		rows = append(rows, models.UserRecoveryCode{UserID: userID, CodeHash: middleware.HashToken(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// GetTwoFactorStatus 查看两步验证状态和剩余恢复码数量
+func (tc *TwoFactorController) GetTwoFactorStatus(c *gin.Context) {
+	user, ok := currentActiveUser(tc.db, c)
+	if !ok {
+		return
+	}
+
+	var remaining int64
+	tc.db.Model(&models.UserRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{
+		"enabled":                  user.TwoFactorEnabled(),
+		"required":                 middleware.TwoFactorRequired(user.Role),
+		"recovery_codes_remaining": remaining,
+	}, "获取两步验证状态成功"))
+}
+
+// SetupTwoFactor 生成新的密钥和二维码地址，需要调用 EnableTwoFactor 确认后才生效
+func (tc *TwoFactorController) SetupTwoFactor(c *gin.Context) {
+	user, ok := currentActiveUser(tc.db, c)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled() {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "已启用两步验证"))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成密钥失败"))
+		return
+	}
+	if err := tc.db.Model(user).Update("totp_secret", secret).Error; err != nil {
+		logger.ErrorLogger.Printf("用户 %d 保存两步验证密钥失败: %v", user.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成密钥失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(config.AppConfig.UserConfig.TOTPIssuer, user.Username, secret),
+	}, "请使用身份验证器扫描二维码"))
+}
+
+// EnableTwoFactor 用身份验证器生成的验证码确认启用，返回只显示一次的恢复码
+func (tc *TwoFactorController) EnableTwoFactor(c *gin.Context) {
+	var req EnableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	user, ok := currentActiveUser(tc.db, c)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled() {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "已启用两步验证"))
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请先获取两步验证密钥"))
+		return
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, req.Code, time.Now(), 1)
+	if !ok {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证码错误"))
+		return
+	}
+
+	var codes []string
+	err := tc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("用户 %d 启用两步验证失败: %v", user.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "启用两步验证失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("用户 %d 启用了两步验证", user.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"recovery_codes": codes}, "两步验证已启用，请妥善保存恢复码"))
+}
+
+// DisableTwoFactor 关闭两步验证，同时删除密钥和恢复码
+func (tc *TwoFactorController) DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	user, ok := currentActiveUser(tc.db, c)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled() {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未启用两步验证"))
+		return
+	}
+
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "密码错误"))
+			return
+		}
+	}
+	if ok, err := verifySecondFactor(tc.db, user, req.Code, req.RecoveryCode); err != nil || !ok {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证码错误"))
+		return
+	}
+
+	err := tc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.UserRecoveryCode{}).Error
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("用户 %d 关闭两步验证失败: %v", user.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "关闭两步验证失败"))
+		return
+	}
+
+	logger.InfoLogger.Printf("用户 %d 关闭了两步验证", user.ID)
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "两步验证已关闭"))
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，需要当前的验证码
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req EnableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	user, ok := currentActiveUser(tc.db, c)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled() {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未启用两步验证"))
+		return
+	}
+
+	if ok, err := verifySecondFactor(tc.db, user, req.Code, ""); err != nil || !ok {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证码错误"))
+		return
+	}
+
+	var codes []string
+	err := tc.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		logger.ErrorLogger.Printf("用户 %d 重新生成恢复码失败: %v", user.ID, err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成恢复码失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"recovery_codes": codes}, "恢复码已重新生成，旧的恢复码已失效"))
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"travel_guide/models"
+	"travel_guide/utils/totp"
+)
+
+func TestVerifySecondFactorRejectsReplay(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "alice", models.RoleUser)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := db.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled_at": now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	codeAt := func(step int64) string {
+		t.Helper()
+		code, err := totp.CodeAt(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	verify := func(code string) bool {
+		t.Helper()
+		ok, err := verifySecondFactor(db, &user, code, "")
+		if err != nil {
+			t.Fatalf("verifySecondFactor: %v", err)
+		}
+		return ok
+	}
+
+	step := totp.Step(now)
+	if !verify(codeAt(step)) {
+		t.Fatal("current code was rejected")
+	}
+	if verify(codeAt(step)) {
+		t.Fatal("the same code was accepted twice")
+	}
+	// 已使用时间步之前的验证码即使仍在容差范围内也不能再用
+	if verify(codeAt(step - 1)) {
+		t.Fatal("code from an earlier step was accepted after a later one")
+	}
+	if !verify(codeAt(step + 1)) {
+		t.Fatal("code from the next step was rejected")
+	}
+
+	var stored models.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.TOTPLastStep != step+1 {
+		t.Fatalf("totp_last_step = %d, want %d", stored.TOTPLastStep, step+1)
+	}
+}
+
+func TestVerifySecondFactorRecoveryCodeSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db, "alice", models.RoleUser)
+	codes, err := replaceRecoveryCodes(db, user.ID)
+	if err != nil {
+		t.Fatalf("replaceRecoveryCodes: %v", err)
+	}
+
+	ok, err := verifySecondFactor(db, &user, "", codes[0])
+	if err != nil || !ok {
+		t.Fatalf("first use = %v, %v", ok, err)
+	}
+	if ok, _ := verifySecondFactor(db, &user, "", codes[0]); ok {
+		t.Fatal("recovery code was accepted twice")
+	}
+}
@@ -1,108 +1,203 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"strings"
 	"time"
 
 	"travel_guide/config"
+	"travel_guide/models"
 	"travel_guide/types"
+	"travel_guide/utils/imageproc"
 	"travel_guide/utils/logger"
+	"travel_guide/utils/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-type UploadController struct{}
+type UploadController struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewUploadController(db *gorm.DB, store storage.Storage) *UploadController {
+	return &UploadController{db: db, store: store}
+}
+
+// multipart 请求中除文件内容外的额外开销上限
+const uploadFormOverhead = 1 << 20
+
+// imageLimits 上传图片的大小限制
+func imageLimits() imageproc.Limits {
+	cfg := config.AppConfig.ImageConfig
+	return imageproc.Limits{MaxBytes: cfg.MaxBytes, MaxPixels: cfg.MaxPixels}
+}
+
+// prepareImage 按内容校验图片并按配置去除元数据，失败时返回带错误码的 imageproc.Error
+func prepareImage(data []byte) ([]byte, *imageproc.Info, error) {
+	info, err := imageproc.Inspect(data, imageLimits())
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.AppConfig.ImageConfig.StripMetadata {
+		stripped, err := imageproc.StripMetadata(data, info.Format)
+		if err != nil {
+			return nil, nil, &imageproc.Error{Code: imageproc.CodeCorrupt, Message: "图片文件已损坏或无法识别"}
+		}
+		data = stripped
+	}
+	return data, info, nil
+}
+
+// newImageKey 生成 images/yyyy/mm/dd/uuid.ext 形式的唯一对象名
+func newImageKey(format imageproc.Format) string {
+	return fmt.Sprintf("images/%s/%s%s", time.Now().Format("2006/01/02"), uuid.New().String(), format.Ext())
+}
+
+// storeImage 保存原图和各尺寸缩略图并记录到 images 表，任一步失败时删除已写入的文件
+func (uc *UploadController) storeImage(ctx context.Context, userID uint, key string, data []byte, info *imageproc.Info) (*models.Image, error) {
+	processed, err := imageproc.Process(data, info, imageproc.DefaultVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	cleanup := func() {
+		for _, k := range written {
+			if err := uc.store.Delete(ctx, k); err != nil {
+				logger.ErrorLogger.Printf("清理文件 %s 失败: %v", k, err)
+			}
+		}
+	}
+	put := func(k string, body []byte, contentType string) error {
+		if err := uc.store.Put(ctx, k, bytes.NewReader(body), int64(len(body)), contentType); err != nil {
+			return err
+		}
+		written = append(written, k)
+		return nil
+	}
+
+	if err := put(key, data, info.Format.ContentType()); err != nil {
+		cleanup()
+		return nil, err
+	}
 
-func NewUploadController() *UploadController {
-	return &UploadController{}
+	base := strings.TrimSuffix(key, path.Ext(key))
+	variants := make([]models.ImageVariant, 0, len(processed.Variants))
+	for _, v := range processed.Variants {
+		variant := models.ImageVariant{
+			Name:   v.Name,
+			Key:    base + "_" + v.Name + v.Format.Ext(),
+			Width:  v.Width,
+			Height: v.Height,
+		}
+		if err := put(variant.Key, v.Data, v.Format.ContentType()); err != nil {
+			cleanup()
+			return nil, err
+		}
+		variant.URL = uc.store.URL(variant.Key)
+		variants = append(variants, variant)
+	}
+
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	image := models.Image{
+		UserID:      userID,
+		ObjectKey:   key,
+		URL:         uc.store.URL(key),
+		Format:      string(info.Format),
+		Width:       processed.Width,
+		Height:      processed.Height,
+		Bytes:       int64(len(data)),
+		Placeholder: processed.Placeholder,
+		Variants:    string(variantsJSON),
+	}
+	if err := uc.db.Create(&image).Error; err != nil {
+		cleanup()
+		return nil, err
+	}
+	return &image, nil
 }
 
-// UploadImage 处理图片上传
+// UploadImage 处理图片上传，文件类型按内容识别，不信任扩展名
 func (uc *UploadController) UploadImage(c *gin.Context) {
 	logger.InfoLogger.Println("开始处理图片上传")
 
+	limits := imageLimits()
+	if limits.MaxBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBytes+uploadFormOverhead)
+	}
+
 	// 获取上传的文件
 	file, err := c.FormFile("image")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusOK, types.CodedErrorResponse(imageproc.CodeTooLarge, "图片文件过大"))
+			return
+		}
 		logger.ErrorLogger.Printf("获取上传文件失败: %v", err)
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "请选择要上传的图片"))
 		return
 	}
 	logger.InfoLogger.Printf("接收到文件: %s, 大小: %d bytes", file.Filename, file.Size)
 
-	// 检查文件类型
-	ext := filepath.Ext(file.Filename)
-	if !isValidImageType(ext) {
-		logger.ErrorLogger.Printf("不支持的文件类型: %s", ext)
-		c.JSON(http.StatusOK, types.ErrorResponse(1, "不支持的文件类型"))
-		return
-	}
-
-	// 生成唯一的文件名
-	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	logger.InfoLogger.Printf("生成的文件名: %s", fileName)
-
-	// 创建临时文件
-	tempFile, err := os.CreateTemp("", "upload-*"+ext)
+	src, err := file.Open()
 	if err != nil {
-		logger.ErrorLogger.Printf("创建临时文件失败: %v", err)
-		c.JSON(http.StatusOK, types.ErrorResponse(1, "创建临时文件失败"))
+		logger.ErrorLogger.Printf("读取上传文件失败: %v", err)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "保存文件失败"))
 		return
 	}
-	defer os.Remove(tempFile.Name())
-	logger.InfoLogger.Printf("创建临时文件: %s", tempFile.Name())
+	defer src.Close()
 
-	// 保存文件到临时目录
-	if err := c.SaveUploadedFile(file, tempFile.Name()); err != nil {
-		logger.ErrorLogger.Printf("保存上传文件失败: %v", err)
+	// 多读一个字节用于判断是否超过限制
+	reader := io.Reader(src)
+	if limits.MaxBytes > 0 {
+		reader = io.LimitReader(src, limits.MaxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		logger.ErrorLogger.Printf("读取上传文件失败: %v", err)
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "保存文件失败"))
 		return
 	}
 
-	// 获取OSS bucket
-	bucketName := os.Getenv("OSS_BUCKET_NAME")
-	logger.InfoLogger.Printf("准备上传到OSS，Bucket: %s", bucketName)
-
-	bucket, err := config.OSSClient.Bucket(bucketName)
+	data, info, err := prepareImage(data)
 	if err != nil {
-		logger.ErrorLogger.Printf("获取OSS bucket失败: %v", err)
-		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取OSS bucket失败"))
+		var imgErr *imageproc.Error
+		if errors.As(err, &imgErr) {
+			logger.ErrorLogger.Printf("图片校验失败: %s (%s)", imgErr.Code, file.Filename)
+			c.JSON(http.StatusOK, types.CodedErrorResponse(imgErr.Code, imgErr.Message))
+			return
+		}
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "上传图片失败"))
 		return
 	}
 
-	// 上传到OSS
-	objectName := fmt.Sprintf("images/%s/%s", time.Now().Format("2006/01/02"), fileName)
-	logger.InfoLogger.Printf("开始上传文件到OSS，对象名: %s", objectName)
+	// 生成唯一的对象名，扩展名以识别出的格式为准
+	objectName := newImageKey(info.Format)
+	logger.InfoLogger.Printf("开始上传文件，对象名: %s", objectName)
 
-	err = bucket.PutObjectFromFile(objectName, tempFile.Name())
+	userID, _ := c.Get("user_id")
+	image, err := uc.storeImage(c.Request.Context(), userID.(uint), objectName, data, info)
 	if err != nil {
-		logger.ErrorLogger.Printf("上传到OSS失败: %v", err)
+		logger.ErrorLogger.Printf("上传文件失败: %v", err)
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "上传图片失败"))
 		return
 	}
+	logger.InfoLogger.Printf("文件上传成功，访问URL: %s", image.URL)
 
-	// 生成访问URL
-	url := fmt.Sprintf("https://%s.%s/%s", bucketName, config.OSSClient.Config.Endpoint, objectName)
-	logger.InfoLogger.Printf("文件上传成功，访问URL: %s", url)
-
-	c.JSON(http.StatusOK, types.SuccessResponse(
-		gin.H{"url": url},
-		"上传成功",
-	))
-}
-
-// isValidImageType 检查文件类型是否为图片
-func isValidImageType(ext string) bool {
-	validTypes := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
-		".webp": true,
-	}
-	return validTypes[ext]
+	c.JSON(http.StatusOK, types.SuccessResponse(toImageResponse(*image), "上传成功"))
 }
@@ -1,33 +1,58 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"travel_guide/config"
 	"travel_guide/middleware"
 	"travel_guide/models"
 	"travel_guide/types"
+	"travel_guide/utils/avatar"
+	"travel_guide/utils/loginguard"
+	"travel_guide/utils/validate"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserController struct {
 	DB *gorm.DB
+
+	// 登录失败记录，按用户名和IP两个维度分别统计
+	loginAttempts loginguard.Store
+	userGuard     *loginguard.Guard
+	ipGuard       *loginguard.Guard
 }
 
 func NewUserController(db *gorm.DB) *UserController {
-	return &UserController{DB: db}
+	store := loginguard.NewMemoryStore(24 * time.Hour)
+	return &UserController{
+		DB:            db,
+		loginAttempts: store,
+		userGuard:     loginguard.NewGuard(store, loginguard.UserPolicy(), "user:"),
+		ipGuard:       loginguard.NewGuard(store, loginguard.IPPolicy(), "ip:"),
+	}
 }
 
+// dummyPasswordHash 用户不存在时也执行一次密码比对，避免通过响应时间枚举用户名
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// CreateUserRequest 注册请求，字段由 validate 包逐项校验
 type CreateUserRequest struct {
-	Username  string `json:"username" binding:"required"`
-	Password  string `json:"password" binding:"required"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
 	Nickname  string `json:"nickname"`
 	AvatarURL string `json:"avatar_url"`
+	Email     string `json:"email"` // 可选，注册后需要验证
+	Phone     string `json:"phone"` // 可选，注册后需要验证
 }
 
 type LoginRequest struct {
@@ -35,18 +60,47 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// LoginTwoFactorRequest 验证码和恢复码二选一
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// UpdateUserRequest 用户修改自己的资料，未传的字段保持不变
 type UpdateUserRequest struct {
-	Nickname  string            `json:"nickname"`
-	AvatarURL string            `json:"avatar_url"`
-	Status    models.UserStatus `json:"status"`
+	Nickname  *string `json:"nickname"`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,max=255"`
+}
+
+// ChangePasswordRequest 第三方登录创建、尚未设置密码的账号不传原密码，
+// 改为传两步验证码或恢复码（已启用时），或在刚登录后操作
+type ChangePasswordRequest struct {
+	OldPassword  string `json:"old_password"`
+	NewPassword  string `json:"new_password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// DeleteAccountRequest 身份确认方式与 ChangePasswordRequest 相同
+type DeleteAccountRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type UpdateUserRoleRequest struct {
-	Role models.UserRole `json:"role" binding:"required,oneof=admin user"`
+	Role models.UserRole `json:"role" binding:"required,max=50"`
 }
 
 type UpdateUserStatusRequest struct {
-	Status models.UserStatus `json:"status" binding:"required,oneof=active banned"`
+	Status      models.UserStatus `json:"status" binding:"required,oneof=active banned"`
+	Reason      string            `json:"reason" binding:"max=255"` // 封禁原因，可选
+	BannedUntil *int64            `json:"banned_until"`             // 封禁到期的秒级时间戳，为空表示永久封禁
 }
 
 type Response struct {
@@ -56,20 +110,56 @@ type Response struct {
 }
 
 type UserListResponse struct {
-	ID         uint      `json:"id"`
-	Username   string    `json:"username"`
-	Nickname   string    `json:"nickname"`
-	AvatarURL  string    `json:"avatar_url"`
-	Role       string    `json:"role"`
-	Status     string    `json:"status"`
-	GuideCount int64     `json:"guide_count"`
-	CreatedAt  time.Time `json:"created_at"`
-	Tags       []string  `json:"tags"`
+	ID          uint       `json:"id"`
+	Username    string     `json:"username"`
+	Nickname    string     `json:"nickname"`
+	AvatarURL   string     `json:"avatar_url"`
+	Role        string     `json:"role"`
+	Status      string     `json:"status"`
+	BanReason   string     `json:"ban_reason,omitempty"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+	GuideCount  int64      `json:"guide_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Tags        []string   `json:"tags"`
 }
 
 type GetUsersRequest struct {
-	Limit  int `form:"limit,default=10"`
-	Offset int `form:"offset,default=0"`
+	Limit          int               `form:"limit,default=10"`
+	Offset         int               `form:"offset,default=0"`
+	IncludeDeleted bool              `form:"include_deleted"`
+	Status         models.UserStatus `form:"status" binding:"omitempty,oneof=active banned"`
+	Role           models.UserRole   `form:"role" binding:"omitempty,max=50"`
+	Keyword        string            `form:"keyword"`                               // 匹配用户名或昵称
+	CreatedFrom    time.Time         `form:"created_from" time_format:"2006-01-02"` // 注册日期起（含）
+	CreatedTo      time.Time         `form:"created_to" time_format:"2006-01-02"`   // 注册日期止（含）
+}
+
+// errLastAdmin 操作会导致系统中没有可用的管理员
+var errLastAdmin = errors.New("不能移除或封禁最后一位管理员")
+
+// ensureNotLastAdmin 在事务中检查除 user 外是否还有未被封禁的管理员，没有则返回 errLastAdmin
+// 封禁已到期但还未被解除的管理员同样计入，与登录时的判断一致
+func ensureNotLastAdmin(tx *gorm.DB, user models.User) error {
+	if user.Role != models.RoleAdmin {
+		return nil
+	}
+
+	// 锁定管理员记录，避免并发降级或封禁时同时通过检查
+	var admins []models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status", "banned_until").
+		Where("role = ? AND id <> ?", models.RoleAdmin, user.ID).
+		Find(&admins).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, admin := range admins {
+		if !admin.IsBanned(now) {
+			return nil
+		}
+	}
+	return errLastAdmin
 }
 
 type PaginatedUserListResponse struct {
@@ -78,17 +168,40 @@ type PaginatedUserListResponse struct {
 	HasMore bool               `json:"has_more"`
 }
 
-func generateDefaultAvatar(nickname string) string {
-	// 使用 DiceBear 的 avatars 风格生成头像
-	// 使用用户名作为种子，确保每个用户有唯一的头像
-	// 使用 SVG 格式，图片清晰且文件小
-	// return fmt.Sprintf("https://api.dicebear.com/7.x/avatars/svg?seed=%s&backgroundType=gradient&backgroundColor=b6e3f4,c0aede,d1d4f9", nickname)
-	// 使用昵称的第一个字符作为头像
-	firstChar := string([]rune(nickname)[0])
-	// 生成一个简单的默认头像URL
-	// 这里使用一个示例URL，您可以根据需要替换为实际的默认头像服务
-	return fmt.Sprintf("https://api.dicebear.com/7.x/initials/svg?seed=%s", firstChar)
+// validateCreateUser 校验注册参数，昵称为空时回退为用户名
+func validateCreateUser(req *CreateUserRequest) validate.Errors {
+	var errs validate.Errors
+	req.Username = strings.TrimSpace(req.Username)
+	req.Nickname = strings.TrimSpace(req.Nickname)
 
+	if msg := validate.Username(req.Username, config.AppConfig.UserConfig.ReservedUsernames); msg != "" {
+		errs.Add("username", msg)
+	}
+	if msg := validate.Password(req.Password, config.AppConfig.UserConfig.PasswordPolicy()); msg != "" {
+		errs.Add("password", msg)
+	}
+	if req.Nickname == "" {
+		req.Nickname = req.Username
+	}
+	if msg := validate.Nickname(req.Nickname); msg != "" {
+		errs.Add("nickname", msg)
+	}
+	if len(req.AvatarURL) > 255 {
+		errs.Add("avatar_url", "头像地址不能超过255个字符")
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email != "" {
+		if msg := validate.Email(req.Email); msg != "" {
+			errs.Add("email", msg)
+		}
+	}
+	req.Phone = strings.TrimSpace(req.Phone)
+	if req.Phone != "" {
+		if msg := validate.Phone(req.Phone); msg != "" {
+			errs.Add("phone", msg)
+		}
+	}
+	return errs
 }
 
 func (uc *UserController) CreateUser(c *gin.Context) {
@@ -98,6 +211,36 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
+	if errs := validateCreateUser(&req); errs.Has() {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(errs))
+		return
+	}
+
+	var count int64
+	uc.DB.Unscoped().Model(&models.User{}).Where("username = ?", req.Username).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(validate.Errors{{Field: "username", Message: "用户名已被使用"}}))
+		return
+	}
+
+	var errs validate.Errors
+	if req.Email != "" {
+		uc.DB.Unscoped().Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
+		if count > 0 {
+			errs.Add("email", "该邮箱已被使用")
+		}
+	}
+	if req.Phone != "" {
+		uc.DB.Unscoped().Model(&models.User{}).Where("phone = ?", req.Phone).Count(&count)
+		if count > 0 {
+			errs.Add("phone", "该手机号已被使用")
+		}
+	}
+	if errs.Has() {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(errs))
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "密码加密失败"))
@@ -107,7 +250,7 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	// 如果用户没有提供头像URL，则生成默认头像
 	avatarURL := req.AvatarURL
 	if avatarURL == "" {
-		avatarURL = generateDefaultAvatar(req.Nickname)
+		avatarURL = avatar.Default(req.Nickname)
 	}
 
 	user := models.User{
@@ -118,6 +261,12 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		Role:      models.RoleUser,
 		Status:    models.StatusActive,
 	}
+	if req.Email != "" {
+		user.Email = &req.Email
+	}
+	if req.Phone != "" {
+		user.Phone = &req.Phone
+	}
 
 	if err := uc.DB.Create(&user).Error; err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "创建用户失败"))
@@ -151,13 +300,41 @@ func (uc *UserController) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	var role models.Role
+	if err := uc.DB.Where("name = ?", req.Role).First(&role).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "角色不存在"))
+		return
+	}
+
 	var user models.User
 	if err := uc.DB.First(&user, id).Error; err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
 		return
 	}
 
-	if err := uc.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+	// 只能在自己权限范围内分配角色，避免越权提升自己或他人的权限
+	operator, ok := currentActiveUser(uc.DB, c)
+	if !ok {
+		return
+	}
+	if !middleware.RoleCovers(uc.DB, operator.Role, req.Role) || !middleware.RoleCovers(uc.DB, operator.Role, user.Role) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无权分配该角色"))
+		return
+	}
+
+	err = uc.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Role != models.RoleAdmin {
+			if err := ensureNotLastAdmin(tx, user); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user).Update("role", req.Role).Error
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "更新用户角色失败"))
 		return
 	}
@@ -171,6 +348,18 @@ func (uc *UserController) UpdateUserRole(c *gin.Context) {
 	))
 }
 
+// banMessage 生成封禁提示，包含原因和到期时间
+func banMessage(user models.User) string {
+	message := "账号已被封禁"
+	if user.BanReason != "" {
+		message += "，原因：" + user.BanReason
+	}
+	if user.BannedUntil != nil {
+		message += "，解封时间：" + user.BannedUntil.Format("2006-01-02 15:04:05")
+	}
+	return message
+}
+
 func (uc *UserController) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -178,18 +367,69 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
+	// 用户名统一转为小写，限流的键与查询使用同一个值；users 表的排序规则不区分大小写
+	ip := c.ClientIP()
+	username := strings.ToLower(strings.TrimSpace(req.Username))
+	if wait, blocked := uc.beginLoginAttempt(username, ip); blocked {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, fmt.Sprintf("登录失败次数过多，请在%d秒后重试", int(wait.Seconds())+1)))
+		return
+	}
+
+	// 用户不存在和密码错误返回相同的提示，避免泄露用户名是否存在
 	var user models.User
-	if err := uc.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+	hash := dummyPasswordHash
+	found := uc.DB.Where("username = ?", username).First(&user).Error == nil
+	if found {
+		hash = []byte(user.Password)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || !found {
+		uc.finishLoginAttempt(username, ip, false)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户名或密码错误"))
 		return
 	}
+	uc.finishLoginAttempt(username, ip, true)
+
+	completeLogin(uc.DB, c, user)
+}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusOK, types.ErrorResponse(1, "密码错误"))
+// completeLogin 凭证校验通过后检查封禁状态，已启用两步验证时返回临时令牌，否则创建会话；
+// 密码登录和第三方登录共用
+func completeLogin(db *gorm.DB, c *gin.Context, user models.User) {
+	if user.IsBanned(time.Now()) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, banMessage(user)))
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID)
+	if user.TwoFactorEnabled() {
+		challenge, err := middleware.GenerateChallengeToken(user.ID, twoFactorChallengeTTL)
+		if err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "生成Token失败"))
+			return
+		}
+		c.JSON(http.StatusOK, types.SuccessResponse(gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		}, "请输入两步验证码"))
+		return
+	}
+
+	issueSession(db, c, user)
+}
+
+// issueSession 创建登录会话并返回令牌
+func issueSession(db *gorm.DB, c *gin.Context, user models.User) {
+
+	// 封禁已到期，恢复为正常状态
+	if user.Status == models.StatusBanned {
+		db.Model(&user).Updates(map[string]interface{}{
+			"status":       models.StatusActive,
+			"ban_reason":   "",
+			"banned_until": nil,
+		})
+		middleware.InvalidateUserStatus(user.ID)
+	}
+
+	tokens, err := middleware.CreateSession(db, user.ID, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "生成Token失败"))
 		return
@@ -197,7 +437,9 @@ func (uc *UserController) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, types.SuccessResponse(
 		gin.H{
-			"token": token,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
 			"user": gin.H{
 				"id":         user.ID,
 				"username":   user.Username,
@@ -206,11 +448,191 @@ func (uc *UserController) Login(c *gin.Context) {
 				"role":       user.Role,
 				"status":     user.Status,
 			},
+			// 角色要求两步验证但尚未启用时，前端应引导用户先完成设置
+			"two_factor_setup_required": middleware.TwoFactorRequired(user.Role) && !user.TwoFactorEnabled(),
 		},
 		"登录成功",
 	))
 }
 
+// LoginTwoFactor 登录第二步：使用临时令牌和验证码（或恢复码）完成登录
+func (uc *UserController) LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	userID, err := middleware.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "登录已超时，请重新登录"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, userID).Error; err != nil || !user.TwoFactorEnabled() {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "登录已超时，请重新登录"))
+		return
+	}
+
+	// 验证码输错与密码输错一起计入用户名维度的失败次数
+	username, ip := strings.ToLower(user.Username), c.ClientIP()
+	if wait, blocked := uc.beginLoginAttempt(username, ip); blocked {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, fmt.Sprintf("登录失败次数过多，请在%d秒后重试", int(wait.Seconds())+1)))
+		return
+	}
+
+	ok, err := verifySecondFactor(uc.DB, &user, req.Code, req.RecoveryCode)
+	if err != nil {
+		uc.userGuard.Done(username)
+		uc.ipGuard.Done(ip)
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证失败"))
+		return
+	}
+	uc.finishLoginAttempt(username, ip, ok)
+	if !ok {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "验证码错误"))
+		return
+	}
+
+	if user.IsBanned(time.Now()) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, banMessage(user)))
+		return
+	}
+	issueSession(uc.DB, c, user)
+}
+
+// beginLoginAttempt 在用户名和IP两个维度预占一次尝试，任一维度处于退避或锁定期时返回需要等待的时间。
+// 未被阻止时必须调用 finishLoginAttempt 结束
+func (uc *UserController) beginLoginAttempt(username, ip string) (time.Duration, bool) {
+	if wait, blocked := uc.ipGuard.Begin(ip); blocked {
+		return wait, true
+	}
+	if wait, blocked := uc.userGuard.Begin(username); blocked {
+		uc.ipGuard.Done(ip)
+		return wait, true
+	}
+	return 0, false
+}
+
+// finishLoginAttempt 结束预占的尝试，失败时两个维度都计数；
+// 成功时只清除用户名维度，防止攻击者用自己的账号登录来重置IP计数
+func (uc *UserController) finishLoginAttempt(username, ip string, ok bool) {
+	if !ok {
+		uc.userGuard.Fail(username)
+		uc.ipGuard.Fail(ip)
+		return
+	}
+	uc.userGuard.Reset(username)
+	uc.ipGuard.Done(ip)
+}
+
+// LoginLockoutResponse 登录失败记录
+type LoginLockoutResponse struct {
+	Key          string    `json:"key"`
+	Failures     int       `json:"failures"`
+	LastFailure  time.Time `json:"last_failure"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Locked       bool      `json:"locked"`
+}
+
+// GetLoginLockouts 管理员查看当前处于退避或锁定状态的用户名和IP
+func (uc *UserController) GetLoginLockouts(c *gin.Context) {
+	now := time.Now()
+	lockouts := make([]LoginLockoutResponse, 0)
+	for key, record := range uc.loginAttempts.List() {
+		if !record.BlockedUntil.After(now) {
+			continue
+		}
+		lockouts = append(lockouts, LoginLockoutResponse{
+			Key:          key,
+			Failures:     record.Failures,
+			LastFailure:  record.LastFailure,
+			BlockedUntil: record.BlockedUntil,
+			Locked:       record.Locked,
+		})
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].BlockedUntil.After(lockouts[j].BlockedUntil)
+	})
+
+	c.JSON(http.StatusOK, types.SuccessResponse(lockouts, "获取成功"))
+}
+
+// ClearLoginLockout 管理员解除某个用户名或IP的登录限制，key 形如 user:alice 或 ip:1.2.3.4
+func (uc *UserController) ClearLoginLockout(c *gin.Context) {
+	key := c.Param("key")
+	if !uc.loginAttempts.Delete(key) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "记录不存在"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "解除成功"))
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌和刷新令牌
+func (uc *UserController) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	tokens, err := middleware.RefreshSession(uc.DB, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, middleware.ErrRefreshTokenReused) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "登录状态已失效，请重新登录"))
+			return
+		}
+		if errors.Is(err, middleware.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "刷新令牌无效或已过期"))
+			return
+		}
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "刷新Token失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(tokens, "刷新Token成功"))
+}
+
+// Logout 退出登录，撤销当前会话
+func (uc *UserController) Logout(c *gin.Context) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	if err := middleware.RevokeSession(uc.DB, sessionID.(uint)); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "退出登录失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "退出登录成功"))
+}
+
+// RevokeUserSessions 撤销指定用户的所有会话（管理员）
+func (uc *UserController) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.Unscoped().First(&user, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	if err := middleware.RevokeUserSessions(uc.DB, user.ID, 0); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "撤销会话失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": user.ID}, "已撤销该用户的所有会话"))
+}
+
 func (uc *UserController) GetUsers(c *gin.Context) {
 	var req GetUsersRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -234,17 +656,43 @@ func (uc *UserController) GetUsers(c *gin.Context) {
 	var users []UserWithCount
 	var total int64
 
+	db := uc.DB
+	if req.IncludeDeleted {
+		db = db.Unscoped()
+	}
+
+	// 筛选条件
+	filters := func(query *gorm.DB) *gorm.DB {
+		if req.Status != "" {
+			query = query.Where("users.status = ?", req.Status)
+		}
+		if req.Role != "" {
+			query = query.Where("users.role = ?", req.Role)
+		}
+		if req.Keyword != "" {
+			query = query.Where("(users.username LIKE ? OR users.nickname LIKE ?)", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+		}
+		if !req.CreatedFrom.IsZero() {
+			query = query.Where("users.created_at >= ?", req.CreatedFrom)
+		}
+		if !req.CreatedTo.IsZero() {
+			query = query.Where("users.created_at < ?", req.CreatedTo.AddDate(0, 0, 1))
+		}
+		return query
+	}
+
 	// 获取总记录数
-	if err := uc.DB.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := db.Model(&models.User{}).Scopes(filters).Count(&total).Error; err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "获取用户总数失败"))
 		return
 	}
 
 	// 获取分页数据
-	if err := uc.DB.Model(&models.User{}).
+	if err := db.Model(&models.User{}).
+		Scopes(filters).
 		Preload("Tags").
 		Select("users.*, COUNT(guides.id) as guide_count").
-		Joins("LEFT JOIN travel_guides as guides ON guides.user_id = users.id").
+		Joins("LEFT JOIN travel_guides as guides ON guides.user_id = users.id AND guides.deleted_at IS NULL").
 		Group("users.id").
 		Offset(req.Offset).
 		Limit(req.Limit + 1). // 多查询一条用于判断是否还有更多
@@ -269,17 +717,23 @@ func (uc *UserController) GetUsers(c *gin.Context) {
 			tagNames[i] = tag.Name
 		}
 
-		userResponses = append(userResponses, UserListResponse{
-			ID:         user.ID,
-			Username:   user.Username,
-			Nickname:   user.Nickname,
-			AvatarURL:  user.AvatarURL,
-			Role:       string(user.Role),
-			Status:     string(user.Status),
-			GuideCount: user.GuideCount,
-			CreatedAt:  user.CreatedAt,
-			Tags:       tagNames,
-		})
+		userResponse := UserListResponse{
+			ID:          user.ID,
+			Username:    user.Username,
+			Nickname:    user.Nickname,
+			AvatarURL:   user.AvatarURL,
+			Role:        string(user.Role),
+			Status:      string(user.Status),
+			BanReason:   user.BanReason,
+			BannedUntil: user.BannedUntil,
+			GuideCount:  user.GuideCount,
+			CreatedAt:   user.CreatedAt,
+			Tags:        tagNames,
+		}
+		if user.DeletedAt.Valid {
+			userResponse.DeletedAt = &user.DeletedAt.Time
+		}
+		userResponses = append(userResponses, userResponse)
 	}
 
 	c.JSON(http.StatusOK, types.SuccessResponse(
@@ -311,16 +765,398 @@ func (uc *UserController) UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
-	if err := uc.DB.Model(&user).Update("status", req.Status).Error; err != nil {
+	// 与分配角色相同，只能处理权限不高于自己的用户
+	operator, ok := currentActiveUser(uc.DB, c)
+	if !ok {
+		return
+	}
+	if !middleware.RoleCovers(uc.DB, operator.Role, user.Role) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无权修改该用户的状态"))
+		return
+	}
+
+	updates := map[string]interface{}{
+		"status":       req.Status,
+		"ban_reason":   "",
+		"banned_until": nil,
+	}
+	if req.Status == models.StatusBanned {
+		if req.BannedUntil != nil {
+			bannedUntil := time.Unix(*req.BannedUntil, 0)
+			if !bannedUntil.After(time.Now()) {
+				c.JSON(http.StatusOK, types.ErrorResponse(1, "封禁到期时间必须晚于当前时间"))
+				return
+			}
+			updates["banned_until"] = bannedUntil
+		}
+		updates["ban_reason"] = req.Reason
+	}
+
+	err = uc.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Status == models.StatusBanned {
+			if err := ensureNotLastAdmin(tx, user); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusOK, types.ErrorResponse(1, "更新用户状态失败"))
 		return
 	}
+	middleware.InvalidateUserStatus(user.ID)
+
+	// 封禁后让该用户已登录的会话全部失效
+	if req.Status == models.StatusBanned {
+		if err := middleware.RevokeUserSessions(uc.DB, user.ID, 0); err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "撤销用户会话失败"))
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, types.SuccessResponse(
 		gin.H{
-			"id":     user.ID,
-			"status": user.Status,
+			"id":           user.ID,
+			"status":       user.Status,
+			"ban_reason":   user.BanReason,
+			"banned_until": user.BannedUntil,
 		},
 		"更新用户状态成功",
 	))
 }
+
+// DeleteUser 软删除用户（管理员）
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return
+	}
+
+	if currentUserID, _ := c.Get("user_id"); currentUserID == uint(id) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "不能删除自己"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	err = uc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureNotLastAdmin(tx, user); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return middleware.RevokeUserSessions(tx, user.ID, 0)
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "删除用户失败"))
+		return
+	}
+	middleware.InvalidateUserStatus(user.ID)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": user.ID}, "删除用户成功"))
+}
+
+// RestoreUser 恢复已删除的用户（管理员）
+func (uc *UserController) RestoreUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.Unscoped().First(&user, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	if !user.DeletedAt.Valid {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户未被删除"))
+		return
+	}
+
+	if err := uc.DB.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "恢复用户失败"))
+		return
+	}
+	middleware.InvalidateUserStatus(user.ID)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": user.ID}, "恢复用户成功"))
+}
+
+// GetUserProfile 获取用户公开资料
+func (uc *UserController) GetUserProfile(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "无效的用户ID"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.Preload("Tags").First(&user, id).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	profile := types.UserProfileResponse{
+		UserResponse: types.UserResponse{
+			ID:        user.ID,
+			Username:  user.Username,
+			Nickname:  user.Nickname,
+			AvatarURL: user.AvatarURL,
+		},
+		PreferredTags: make([]types.TagResponse, 0, len(user.Tags)),
+	}
+	for _, tag := range user.Tags {
+		profile.PreferredTags = append(profile.PreferredTags, types.TagResponse{
+			ID:   tag.ID,
+			Name: tag.Name,
+		})
+	}
+
+	uc.DB.Model(&models.TravelGuide{}).
+		Scopes(publishedGuides).
+		Where("user_id = ?", user.ID).
+		Count(&profile.GuideCount)
+	uc.DB.Model(&models.UserFollow{}).Where("followee_id = ?", user.ID).Count(&profile.FollowerCount)
+	uc.DB.Model(&models.UserFollow{}).Where("follower_id = ?", user.ID).Count(&profile.FollowingCount)
+
+	if viewerID, exists := c.Get("user_id"); exists {
+		var following int64
+		uc.DB.Model(&models.UserFollow{}).
+			Where("follower_id = ? AND followee_id = ?", viewerID, user.ID).
+			Count(&following)
+		profile.Following = following > 0
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(profile, "获取用户资料成功"))
+}
+
+func toCurrentUserResponse(user models.User) gin.H {
+	return gin.H{
+		"id":         user.ID,
+		"username":   user.Username,
+		"nickname":   user.Nickname,
+		"avatar_url": user.AvatarURL,
+		"role":       user.Role,
+		"status":     user.Status,
+		"created_at": user.CreatedAt,
+
+		"email":          user.Email,
+		"email_verified": user.EmailVerifiedAt != nil,
+		"phone":          user.Phone,
+		"phone_verified": user.PhoneVerifiedAt != nil,
+
+		"two_factor_enabled":  user.TwoFactorEnabled(),
+		"two_factor_required": middleware.TwoFactorRequired(user.Role),
+	}
+}
+
+// GetCurrentUser 获取当前登录用户的资料
+func (uc *UserController) GetCurrentUser(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	response := toCurrentUserResponse(user)
+	response["permissions"] = middleware.RolePermissionList(uc.DB, middleware.EffectiveRole(user))
+	c.JSON(http.StatusOK, types.SuccessResponse(response, "获取用户信息成功"))
+}
+
+// UpdateCurrentUser 修改当前用户的昵称和头像
+func (uc *UserController) UpdateCurrentUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	var errs validate.Errors
+	if req.Nickname != nil {
+		*req.Nickname = strings.TrimSpace(*req.Nickname)
+		if *req.Nickname == "" {
+			errs.Add("nickname", "昵称不能为空")
+		} else if msg := validate.Nickname(*req.Nickname); msg != "" {
+			errs.Add("nickname", msg)
+		}
+	}
+	if req.AvatarURL != nil && len(*req.AvatarURL) > 255 {
+		errs.Add("avatar_url", "头像地址不能超过255个字符")
+	}
+	if errs.Has() {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(errs))
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Nickname != nil {
+		updates["nickname"] = *req.Nickname
+	}
+	if req.AvatarURL != nil {
+		avatarURL := *req.AvatarURL
+		if avatarURL == "" {
+			avatarURL = avatar.Default(user.Nickname)
+			if req.Nickname != nil {
+				avatarURL = avatar.Default(*req.Nickname)
+			}
+		}
+		updates["avatar_url"] = avatarURL
+	}
+
+	if len(updates) > 0 {
+		if err := uc.DB.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "更新用户信息失败"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(toCurrentUserResponse(user), "更新用户信息成功"))
+}
+
+// ChangePassword 校验旧密码后修改密码
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	// 原密码错误与登录失败一起计入用户名维度的失败次数
+	username, ip := strings.ToLower(user.Username), c.ClientIP()
+	if wait, blocked := uc.beginLoginAttempt(username, ip); blocked {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, fmt.Sprintf("尝试次数过多，请在%d秒后重试", int(wait.Seconds())+1)))
+		return
+	}
+	msg, ok := verifyIdentity(uc.DB, c, &user, req.OldPassword, req.Code, req.RecoveryCode)
+	uc.finishLoginAttempt(username, ip, ok)
+	if !ok {
+		if user.Password != "" {
+			msg = "原密码错误"
+		}
+		c.JSON(http.StatusOK, types.ErrorResponse(1, msg))
+		return
+	}
+
+	if req.OldPassword == req.NewPassword {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "新密码不能与原密码相同"))
+		return
+	}
+
+	if msg := validate.Password(req.NewPassword, config.AppConfig.UserConfig.PasswordPolicy()); msg != "" {
+		c.JSON(http.StatusOK, types.ValidationErrorResponse(validate.Errors{{Field: "new_password", Message: msg}}))
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "密码加密失败"))
+		return
+	}
+
+	if err := uc.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "修改密码失败"))
+		return
+	}
+
+	// 修改密码后其他设备需要重新登录
+	sessionID, _ := c.Get("session_id")
+	if err := middleware.RevokeUserSessions(uc.DB, user.ID, sessionID.(uint)); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "撤销其他会话失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SuccessResponse(nil, "修改密码成功"))
+}
+
+// DeleteCurrentUser 注销当前账号，软删除用户后其攻略不再公开展示
+func (uc *UserController) DeleteCurrentUser(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "请求参数错误"))
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "未授权"))
+		return
+	}
+
+	var user models.User
+	if err := uc.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "用户不存在"))
+		return
+	}
+
+	if msg, ok := verifyIdentity(uc.DB, c, &user, req.Password, req.Code, req.RecoveryCode); !ok {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, msg))
+		return
+	}
+
+	err := uc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureNotLastAdmin(tx, user); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return middleware.RevokeUserSessions(tx, user.ID, 0)
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "注销账号失败"))
+		return
+	}
+	middleware.InvalidateUserStatus(user.ID)
+
+	c.JSON(http.StatusOK, types.SuccessResponse(gin.H{"id": user.ID}, "账号已注销"))
+}
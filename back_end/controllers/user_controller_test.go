@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"travel_guide/models"
+)
+
+func TestEnsureNotLastAdmin(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "admin", models.RoleAdmin)
+	other := createTestUser(t, db, "other", models.RoleAdmin)
+
+	ban := func(until *time.Time) {
+		t.Helper()
+		if err := db.Model(&other).Updates(map[string]interface{}{"status": models.StatusBanned, "banned_until": until}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ensureNotLastAdmin(db, admin); err != nil {
+		t.Fatalf("with another active admin: %v", err)
+	}
+
+	ban(nil)
+	if err := ensureNotLastAdmin(db, admin); !errors.Is(err, errLastAdmin) {
+		t.Fatalf("with the other admin banned permanently: %v, want errLastAdmin", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	ban(&future)
+	if err := ensureNotLastAdmin(db, admin); !errors.Is(err, errLastAdmin) {
+		t.Fatalf("with the other admin banned: %v, want errLastAdmin", err)
+	}
+
+	// 封禁已到期但状态还未恢复的管理员仍然有效
+	past := time.Now().Add(-time.Hour)
+	ban(&past)
+	if err := ensureNotLastAdmin(db, admin); err != nil {
+		t.Fatalf("with the other admin's ban expired: %v", err)
+	}
+
+	if err := ensureNotLastAdmin(db, models.User{ID: other.ID, Role: models.RoleUser}); err != nil {
+		t.Fatalf("non-admin user: %v", err)
+	}
+}
@@ -9,7 +9,10 @@ github.com/golang-jwt/jwt/v5 v5.0.0
 github.com/google/uuid v1.6.0
 github.com/joho/godotenv v1.5.1
 golang.org/x/crypto v0.14.0
+golang.org/x/image v0.15.0
+golang.org/x/term v0.13.0
 gorm.io/driver/mysql v1.5.2
+gorm.io/driver/sqlite v1.5.4
 gorm.io/gorm v1.25.5
 )
 
@@ -29,6 +32,7 @@ github.com/json-iterator/go v1.1.12 // indirect
 github.com/klauspost/cpuid/v2 v2.2.4 // indirect
 github.com/leodido/go-urn v1.2.4 // indirect
 github.com/mattn/go-isatty v0.0.19 // indirect
+github.com/mattn/go-sqlite3 v1.14.17 // indirect
 github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 github.com/modern-go/reflect2 v1.0.2 // indirect
 github.com/pelletier/go-toml/v2 v2.0.8 // indirect
@@ -37,7 +41,7 @@ github.com/ugorji/go/codec v1.2.11 // indirect
 golang.org/x/arch v0.3.0 // indirect
 golang.org/x/net v0.10.0 // indirect
 golang.org/x/sys v0.13.0 // indirect
-golang.org/x/text v0.13.0 // indirect
+golang.org/x/text v0.14.0 // indirect
 golang.org/x/time v0.3.0 // indirect
 google.golang.org/protobuf v1.30.0 // indirect
 gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"time"
+
+	"travel_guide/models"
+	"travel_guide/utils/logger"
+
+	"gorm.io/gorm"
+)
+
+// StartBanLifter 启动后台协程，按 interval 定期解除已到期的临时封禁
+func StartBanLifter(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			liftExpiredBans(db)
+			<-ticker.C
+		}
+	}()
+}
+
+// liftExpiredBans 将封禁到期的用户恢复为正常状态
+func liftExpiredBans(db *gorm.DB) {
+	result := db.Model(&models.User{}).
+		Where("status = ? AND banned_until IS NOT NULL AND banned_until <= ?", models.StatusBanned, time.Now()).
+		Updates(map[string]interface{}{
+			"status":       models.StatusActive,
+			"ban_reason":   "",
+			"banned_until": nil,
+		})
+	if result.Error != nil {
+		logger.ErrorLogger.Printf("解除到期封禁失败: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		logger.InfoLogger.Printf("已解除 %d 个到期的封禁", result.RowsAffected)
+	}
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"time"
+
+	"travel_guide/models"
+	"travel_guide/utils/logger"
+
+	"gorm.io/gorm"
+)
+
+// StartGuidePublisher 启动后台协程，按 interval 定期发布已到时间的定时攻略
+func StartGuidePublisher(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			publishScheduledGuides(db)
+			<-ticker.C
+		}
+	}()
+}
+
+// publishScheduledGuides 将发布时间已到的定时攻略改为已发布
+func publishScheduledGuides(db *gorm.DB) {
+	result := db.Model(&models.TravelGuide{}).
+		Where("status = ? AND published_at <= ?", models.GuideStatusScheduled, time.Now()).
+		Update("status", models.GuideStatusPublished)
+	if result.Error != nil {
+		logger.ErrorLogger.Printf("发布定时攻略失败: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		logger.InfoLogger.Printf("已发布 %d 篇定时攻略", result.RowsAffected)
+	}
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"travel_guide/models"
+	"travel_guide/utils/logger"
+	"travel_guide/utils/storage"
+
+	"gorm.io/gorm"
+)
+
+// 每轮最多清理的直传记录数量
+const uploadCleanupBatch = 100
+
+// StartUploadCleaner 启动后台协程，按 interval 定期删除过期未确认的直传对象
+func StartUploadCleaner(db *gorm.DB, store storage.Storage, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			cleanExpiredUploads(db, store)
+			<-ticker.C
+		}
+	}()
+}
+
+// cleanExpiredUploads 删除过期的直传记录及客户端可能已上传的对象，对象删除失败时保留记录下次重试
+func cleanExpiredUploads(db *gorm.DB, store storage.Storage) {
+	var uploads []models.PendingUpload
+	if err := db.Where("expires_at <= ?", time.Now()).Limit(uploadCleanupBatch).Find(&uploads).Error; err != nil {
+		logger.ErrorLogger.Printf("获取过期直传记录失败: %v", err)
+		return
+	}
+
+	cleaned := 0
+	for _, upload := range uploads {
+		if err := store.Delete(context.Background(), upload.ObjectKey); err != nil {
+			logger.ErrorLogger.Printf("删除过期直传对象 %s 失败: %v", upload.ObjectKey, err)
+			continue
+		}
+		if err := db.Delete(&upload).Error; err != nil {
+			logger.ErrorLogger.Printf("删除过期直传记录 %d 失败: %v", upload.ID, err)
+			continue
+		}
+		cleaned++
+	}
+
+	if cleaned > 0 {
+		logger.InfoLogger.Printf("已清理 %d 个过期未确认的直传", cleaned)
+	}
+}
@@ -3,8 +3,12 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"time"
 	"travel_guide/config"
+	"travel_guide/jobs"
 	"travel_guide/routes"
+	"travel_guide/seed"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,16 +25,38 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	// 初始化oss
-	if err := config.InitOSS(); err != nil {
-		log.Fatal("Failed to initialize OSS client:", err)
+	// go run . bootstrap-admin：交互式创建首个管理员后退出
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap-admin" {
+		if err := seed.PromptAdmin(db, os.Stdin, os.Stdout); err != nil {
+			log.Fatal("Failed to create admin user:", err)
+		}
+		return
 	}
 
+	// 导入当前环境的演示数据（需开启 SEED_ENABLED），并在没有管理员时按环境变量创建
+	if err := seed.Run(db, config.AppConfig.SeedConfig); err != nil {
+		log.Fatal("Failed to seed database:", err)
+	}
+	if err := seed.BootstrapAdmin(db, config.AppConfig.SeedConfig); err != nil {
+		log.Fatal("Failed to bootstrap admin user:", err)
+	}
+
+	// 初始化文件存储，配置的存储不可用时直接退出
+	store, err := config.NewStorage()
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	// 启动后台任务：定时发布攻略、解除到期封禁、清理未确认的直传
+	jobs.StartGuidePublisher(db, time.Minute)
+	jobs.StartBanLifter(db, time.Minute)
+	jobs.StartUploadCleaner(db, store, 10*time.Minute)
+
 	// 初始化路由
 	r := gin.Default()
 
 	// 设置路由
-	routes.SetupRoutes(r, db)
+	routes.SetupRoutes(r, db, store)
 
 	//启动server
 	addr := fmt.Sprintf(":%d", config.AppConfig.ServerConfig.Port)
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"travel_guide/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix API 密钥的固定前缀，用于与 JWT 区分，也方便密钥泄露扫描
+const APIKeyPrefix = "tg_"
+
+// API 密钥最近使用时间的写入间隔，避免每次请求都更新数据库
+const apiKeyTouchInterval = time.Minute
+
+var (
+	// ErrInvalidAPIKey API 密钥不存在、已撤销或已过期
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyScope API 密钥没有访问该接口的授权范围
+	ErrAPIKeyScope = errors.New("api key scope not allowed")
+)
+
+// NewAPIKey 生成随机 API 密钥，返回明文、展示用前缀和哈希
+func NewAPIKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:len(APIKeyPrefix)+6], HashToken(key), nil
+}
+
+// IsAPIKey 判断字符串是否是 API 密钥格式
+func IsAPIKey(value string) bool {
+	return strings.HasPrefix(value, APIKeyPrefix)
+}
+
+// apiKeyFromRequest 从 X-API-Key 头或 "Bearer tg_..." 中取出 API 密钥
+func apiKeyFromRequest(header, authorization string) string {
+	if header != "" {
+		return header
+	}
+	parts := strings.Split(authorization, " ")
+	if len(parts) == 2 && parts[0] == "Bearer" && IsAPIKey(parts[1]) {
+		return parts[1]
+	}
+	return ""
+}
+
+// authenticateAPIKey 校验 API 密钥及其授权范围，密钥需包含 scopes 中的任意一个
+func authenticateAPIKey(db *gorm.DB, key string, scopes []string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	if err := db.Where("key_hash = ?", HashToken(key)).First(&apiKey).Error; err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	allowed := false
+	for _, scope := range scopes {
+		if apiKey.HasScope(scope) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, ErrAPIKeyScope
+	}
+
+	// 条件更新，多个并发请求在同一间隔内只会写入一次
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		db.Model(&models.APIKey{}).
+			Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-apiKeyTouchInterval)).
+			Update("last_used_at", now)
+	}
+
+	return &apiKey, nil
+}
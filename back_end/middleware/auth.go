@@ -1,12 +1,12 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
-	"travel_guide/models"
+	"travel_guide/config"
 	"travel_guide/types"
 
 	"github.com/gin-gonic/gin"
@@ -16,15 +16,17 @@ import (
 
 // JWTClaims 自定义JWT声明
 type JWTClaims struct {
-	UserID uint `json:"user_id"`
+	UserID    uint `json:"user_id"`
+	SessionID uint `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(userID uint) (string, error) {
-	expiresIn, _ := strconv.ParseInt(getEnv("JWT_EXPIRES_IN", "86400"), 10, 64)
+// GenerateToken 生成绑定到会话的短期访问令牌
+func GenerateToken(userID, sessionID uint) (string, error) {
+	expiresIn := config.AppConfig.JWTConfig.ExpiresIn
 	claims := JWTClaims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiresIn) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -36,101 +38,179 @@ func GenerateToken(userID uint) (string, error) {
 	return token.SignedString([]byte(getEnv("JWT_SECRET_KEY", "")))
 }
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusOK, types.ErrorResponse(1, "Authorization header is required"))
-			c.Abort()
-			return
-		}
+// 两步验证临时令牌的受众，与访问令牌区分
+const challengeAudience = "2fa"
 
-		// 检查Bearer token格式
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusOK, types.ErrorResponse(1, "Invalid authorization header format"))
-			c.Abort()
-			return
-		}
+// ErrInvalidChallenge 两步验证临时令牌无效或已过期
+var ErrInvalidChallenge = errors.New("invalid two-factor challenge")
 
-		tokenString := parts[1]
-		claims := &JWTClaims{}
+// GenerateChallengeToken 密码校验通过但需要两步验证时签发的临时令牌，不绑定会话，不能用于访问接口
+func GenerateChallengeToken(userID uint, ttl time.Duration) (string, error) {
+	claims := JWTClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(getEnv("JWT_SECRET_KEY", "")), nil
-		})
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(getEnv("JWT_SECRET_KEY", "")))
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusOK, types.ErrorResponse(1, "Invalid token"))
-			c.Abort()
-			return
-		}
+// ParseChallengeToken 校验两步验证临时令牌，返回用户ID
+func ParseChallengeToken(tokenString string) (uint, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(getEnv("JWT_SECRET_KEY", "")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(challengeAudience))
+	if err != nil || !token.Valid || claims.SessionID != 0 {
+		return 0, ErrInvalidChallenge
+	}
+	return claims.UserID, nil
+}
 
-		// 将用户ID存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Next()
+// parseBearerToken 从 Authorization 头中解析并校验JWT，失败时返回错误信息
+func parseBearerToken(c *gin.Context) (*JWTClaims, string) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return nil, "Authorization header is required"
+	}
+
+	// 检查Bearer token格式
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, "Invalid authorization header format"
+	}
+
+	tokenString := parts[1]
+	claims := &JWTClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(getEnv("JWT_SECRET_KEY", "")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil || !token.Valid {
+		return nil, "Invalid token"
 	}
+
+	return claims, ""
 }
 
-// AdminMiddleware checks if the user has admin role
-func AdminMiddleware(db *gorm.DB) gin.HandlerFunc {
+// AuthMiddleware JWT认证中间件，令牌所属会话被撤销后立即拒绝
+// scopes 为该接口接受的 API 密钥授权范围，不传时只接受 JWT
+func AuthMiddleware(db *gorm.DB, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.JSON(http.StatusOK, types.ErrorResponse(1, "Unauthorized"))
-			c.Abort()
+		if key := apiKeyFromRequest(c.GetHeader("X-API-Key"), c.GetHeader("Authorization")); key != "" {
+			if len(scopes) == 0 {
+				c.JSON(http.StatusOK, types.ErrorResponse(1, "API key is not accepted for this endpoint"))
+				c.Abort()
+				return
+			}
+			apiKey, err := authenticateAPIKey(db, key, scopes)
+			if err != nil {
+				message := "Invalid API key"
+				if errors.Is(err, ErrAPIKeyScope) {
+					message = "API key scope not allowed"
+				}
+				c.JSON(http.StatusOK, types.ErrorResponse(1, message))
+				c.Abort()
+				return
+			}
+			if !checkUserStatus(c, db, apiKey.UserID) {
+				return
+			}
+
+			c.Set("user_id", apiKey.UserID)
+			c.Set("session_id", uint(0))
+			c.Set("api_key_id", apiKey.ID)
+			c.Next()
 			return
 		}
 
-		// Get user from database
-		var user models.User
-		if err := db.First(&user, userID).Error; err != nil {
-			c.JSON(http.StatusOK, types.ErrorResponse(1, "User not found"))
+		claims, message := parseBearerToken(c)
+		if claims == nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, message))
 			c.Abort()
 			return
 		}
 
-		if user.Role != models.RoleAdmin {
-			c.JSON(http.StatusOK, types.ErrorResponse(1, "Admin access required"))
+		if !isSessionActive(db, claims.SessionID, claims.UserID) {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "Token has been revoked"))
 			c.Abort()
 			return
 		}
 
+		if !checkUserStatus(c, db, claims.UserID) {
+			return
+		}
+
+		// 将用户ID存储到上下文中
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
 
+// checkUserStatus 拒绝已删除或被封禁的用户，失败时已中止请求
+func checkUserStatus(c *gin.Context, db *gorm.DB, userID uint) bool {
+	exists, banned := lookupUserStatus(db, userID)
+	if !exists {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "User not found"))
+		c.Abort()
+		return false
+	}
+	if banned {
+		c.JSON(http.StatusOK, types.ErrorResponse(1, "User is banned"))
+		c.Abort()
+		return false
+	}
+	return true
+}
+
 // OptionalAuthMiddleware adds user info to context if available but doesn't require authentication
-func OptionalAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+// API keys are only honoured when the route lists a matching scope
+func OptionalAuthMiddleware(db *gorm.DB, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if key := apiKeyFromRequest(c.GetHeader("X-API-Key"), c.GetHeader("Authorization")); key != "" {
+			if len(scopes) == 0 {
+				c.Next()
+				return
+			}
+			apiKey, err := authenticateAPIKey(db, key, scopes)
+			if err != nil {
+				c.Next()
+				return
+			}
+			if exists, banned := lookupUserStatus(db, apiKey.UserID); !exists || banned {
+				c.Next()
+				return
+			}
+
+			c.Set("user_id", apiKey.UserID)
+			c.Set("session_id", uint(0))
+			c.Set("api_key_id", apiKey.ID)
 			c.Next()
 			return
 		}
 
-		// 检查Bearer token格式
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		claims, _ := parseBearerToken(c)
+		if claims == nil || !isSessionActive(db, claims.SessionID, claims.UserID) {
 			c.Next()
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &JWTClaims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(getEnv("JWT_SECRET_KEY", "")), nil
-		})
-
-		if err != nil || !token.Valid {
+		// 已删除或被封禁的用户按未登录处理
+		if exists, banned := lookupUserStatus(db, claims.UserID); !exists || banned {
 			c.Next()
 			return
 		}
 
 		// 将用户ID存储到上下文中
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"travel_guide/config"
+	"travel_guide/models"
+	"travel_guide/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 角色权限缓存，角色定义修改后通过 InvalidateRolePermissions 清空
+var rolePermissionCache = struct {
+	sync.RWMutex
+	roles map[models.UserRole]map[string]bool
+}{roles: make(map[models.UserRole]map[string]bool)}
+
+// rolePermissions 返回角色拥有的权限集合，角色不存在时返回空集合
+func rolePermissions(db *gorm.DB, role models.UserRole) map[string]bool {
+	rolePermissionCache.RLock()
+	perms, ok := rolePermissionCache.roles[role]
+	rolePermissionCache.RUnlock()
+	if ok {
+		return perms
+	}
+
+	perms = make(map[string]bool)
+	var names []string
+	err := db.Model(&models.RolePermission{}).
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", role).
+		Pluck("role_permissions.permission", &names).Error
+	if err != nil {
+		// 查询失败时不缓存，下次重新加载
+		return perms
+	}
+	for _, name := range names {
+		perms[name] = true
+	}
+
+	rolePermissionCache.Lock()
+	rolePermissionCache.roles[role] = perms
+	rolePermissionCache.Unlock()
+	return perms
+}
+
+// InvalidateRolePermissions 清空角色权限缓存
+func InvalidateRolePermissions() {
+	rolePermissionCache.Lock()
+	rolePermissionCache.roles = make(map[models.UserRole]map[string]bool)
+	rolePermissionCache.Unlock()
+}
+
+// RoleHasPermission 判断角色是否拥有某项权限
+func RoleHasPermission(db *gorm.DB, role models.UserRole, permission string) bool {
+	perms := rolePermissions(db, role)
+	return perms[models.PermissionAll] || perms[permission]
+}
+
+// RoleCovers 判断角色 role 是否拥有 other 的全部权限，用于防止分配比自己权限更高的角色
+func RoleCovers(db *gorm.DB, role, other models.UserRole) bool {
+	perms := rolePermissions(db, role)
+	if perms[models.PermissionAll] {
+		return true
+	}
+	for name := range rolePermissions(db, other) {
+		if !perms[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// RoleCoversPermissions 判断角色 role 是否拥有 permissions 中的全部权限，用于防止创建或修改出比自己权限更高的角色
+func RoleCoversPermissions(db *gorm.DB, role models.UserRole, permissions []string) bool {
+	perms := rolePermissions(db, role)
+	if perms[models.PermissionAll] {
+		return true
+	}
+	for _, name := range permissions {
+		if !perms[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// RolePermissionList 返回角色的权限列表，管理员只返回 "*"
+func RolePermissionList(db *gorm.DB, role models.UserRole) []string {
+	perms := rolePermissions(db, role)
+	list := make([]string, 0, len(perms))
+	for name := range perms {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// TwoFactorRequired 判断角色是否必须启用两步验证才能使用其权限
+func TwoFactorRequired(role models.UserRole) bool {
+	for _, r := range config.AppConfig.UserConfig.TwoFactorRoles {
+		if models.UserRole(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRole 返回用户实际生效的角色：要求两步验证但尚未启用时，只按普通用户处理
+func EffectiveRole(user models.User) models.UserRole {
+	if TwoFactorRequired(user.Role) && !user.TwoFactorEnabled() {
+		return models.RoleUser
+	}
+	return user.Role
+}
+
+// UserHasPermission 判断用户是否拥有某项权限
+func UserHasPermission(db *gorm.DB, userID interface{}, permission string) bool {
+	var user models.User
+	if err := db.Select("id", "role", "totp_enabled_at").First(&user, userID).Error; err != nil {
+		return false
+	}
+	return RoleHasPermission(db, EffectiveRole(user), permission)
+}
+
+// RequirePermission requires the authenticated user to hold every listed permission
+func RequirePermission(db *gorm.DB, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "Unauthorized"))
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("id", "role", "totp_enabled_at").First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "User not found"))
+			c.Abort()
+			return
+		}
+
+		if TwoFactorRequired(user.Role) && !user.TwoFactorEnabled() {
+			c.JSON(http.StatusOK, types.ErrorResponse(1, "Two-factor authentication required"))
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !RoleHasPermission(db, user.Role, permission) {
+				c.JSON(http.StatusOK, types.ErrorResponse(1, "Permission denied"))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
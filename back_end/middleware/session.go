@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+	"travel_guide/config"
+	"travel_guide/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌不存在、已过期或会话已被撤销
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused 已轮换掉的刷新令牌被再次使用，会话已被撤销
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// TokenPair 登录或刷新后返回给客户端的令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// newRefreshToken 生成随机刷新令牌，数据库中只保存其哈希
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken 计算令牌的 SHA-256，数据库中只保存哈希
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func refreshExpiresAt() time.Time {
+	return time.Now().Add(time.Duration(config.AppConfig.JWTConfig.RefreshExpiresIn) * time.Second)
+}
+
+func newTokenPair(userID, sessionID uint, refreshToken string) (*TokenPair, error) {
+	accessToken, err := GenerateToken(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    config.AppConfig.JWTConfig.ExpiresIn,
+	}, nil
+}
+
+// CreateSession 为用户创建新的登录会话并签发令牌
+func CreateSession(db *gorm.DB, userID uint, userAgent, ip string) (*TokenPair, error) {
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := models.UserSession{
+		UserID:           userID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        userAgent,
+		IP:               ip,
+		ExpiresAt:        refreshExpiresAt(),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	return newTokenPair(userID, session.ID, refreshToken)
+}
+
+// RefreshSession 使用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+func RefreshSession(db *gorm.DB, refreshToken string) (*TokenPair, error) {
+	hash := HashToken(refreshToken)
+
+	var session models.UserSession
+	if err := db.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		// 已轮换掉的令牌被再次使用，可能已经泄露，撤销整个会话
+		if db.Where("previous_token_hash = ?", hash).First(&session).Error == nil {
+			_ = RevokeSession(db, session.ID)
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := db.First(&user, session.UserID).Error; err != nil || user.IsBanned(time.Now()) {
+		_ = RevokeSession(db, session.ID)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// 条件更新，并发刷新时只有一个请求能成功
+	result := db.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, hash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  newHash,
+			"previous_token_hash": hash,
+			"expires_at":          refreshExpiresAt(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return newTokenPair(user.ID, session.ID, newToken)
+}
+
+// RevokeSession 撤销单个会话
+func RevokeSession(db *gorm.DB, sessionID uint) error {
+	return db.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions 撤销用户的所有会话，exceptSessionID 不为 0 时保留该会话
+func RevokeUserSessions(db *gorm.DB, userID, exceptSessionID uint) error {
+	query := db.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptSessionID != 0 {
+		query = query.Where("id <> ?", exceptSessionID)
+	}
+	return query.Update("revoked_at", time.Now()).Error
+}
+
+// isSessionActive 检查访问令牌所属的会话是否仍然有效
+func isSessionActive(db *gorm.DB, sessionID, userID uint) bool {
+	if sessionID == 0 {
+		return false
+	}
+
+	var count int64
+	db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+	"travel_guide/models"
+
+	"gorm.io/gorm"
+)
+
+// 用户状态缓存时间，封禁通过 InvalidateUserStatus 立即生效
+const userStatusCacheTTL = 30 * time.Second
+
+type userStatusEntry struct {
+	exists    bool
+	banned    bool
+	expiresAt time.Time
+}
+
+var userStatusCache = struct {
+	sync.RWMutex
+	entries map[uint]userStatusEntry
+}{entries: make(map[uint]userStatusEntry)}
+
+// lookupUserStatus 返回用户是否存在以及是否处于封禁状态，结果会缓存一段时间
+func lookupUserStatus(db *gorm.DB, userID uint) (exists bool, banned bool) {
+	now := time.Now()
+
+	userStatusCache.RLock()
+	entry, ok := userStatusCache.entries[userID]
+	userStatusCache.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.exists, entry.banned
+	}
+
+	entry = userStatusEntry{expiresAt: now.Add(userStatusCacheTTL)}
+	var user models.User
+	if err := db.Select("id", "status", "banned_until").First(&user, userID).Error; err == nil {
+		entry.exists = true
+		entry.banned = user.IsBanned(now)
+		// 临时封禁在缓存期内到期时，按到期时间提前失效
+		if entry.banned && user.BannedUntil != nil && user.BannedUntil.Before(entry.expiresAt) {
+			entry.expiresAt = *user.BannedUntil
+		}
+	}
+
+	userStatusCache.Lock()
+	userStatusCache.entries[userID] = entry
+	userStatusCache.Unlock()
+
+	return entry.exists, entry.banned
+}
+
+// InvalidateUserStatus 在用户状态变化（封禁、解封、删除）后清除缓存
+func InvalidateUserStatus(userID uint) {
+	userStatusCache.Lock()
+	delete(userStatusCache.entries, userID)
+	userStatusCache.Unlock()
+}
@@ -1,15 +1,22 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // UserRole represents the role of a user
 type UserRole string
 
+// 内置角色，其余角色可以通过角色管理接口创建
 const (
-	RoleAdmin UserRole = "admin" //管理员
-	RoleUser  UserRole = "user"  //普通用户
+	RoleAdmin     UserRole = "admin"     //管理员
+	RoleModerator UserRole = "moderator" //版主
+	RoleEditor    UserRole = "editor"    //编辑
+	RoleUser      UserRole = "user"      //普通用户
 )
 
 // UserStatus represents the status of a user
@@ -20,33 +27,53 @@ const (
 	StatusBanned UserStatus = "banned" //封禁
 )
 
+// GuideStatus represents the publishing status of a travel guide
+type GuideStatus string
+
+const (
+	GuideStatusDraft     GuideStatus = "draft"     //草稿
+	GuideStatusScheduled GuideStatus = "scheduled" //定时发布
+	GuideStatusPublished GuideStatus = "published" //已发布
+	GuideStatusArchived  GuideStatus = "archived"  //已归档
+)
+
 type User struct {
-	ID        uint       `gorm:"primaryKey;autoIncrement"`
-	Username  string     `gorm:"unique;not null;size:50"`
-	Password  string     `gorm:"not null;size:255"`
-	Nickname  string     `gorm:"not null;size:100"`
-	AvatarURL string     `gorm:"size:255"`
-	Role      UserRole   `gorm:"type:enum('admin','user');not null;default:'user'"`
-	Status    UserStatus `gorm:"type:enum('active','banned');not null;default:'active'"`
-	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
-	DeletedAt *time.Time
-	Guides    []TravelGuide
-	Tags      []Tag `gorm:"many2many:user_tags;joinForeignKey:user_id;joinReferences:tag_id"`
+	ID              uint    `gorm:"primaryKey;autoIncrement"`
+	Username        string  `gorm:"unique;not null;size:50"`
+	Password        string  `gorm:"not null;size:255"`
+	Nickname        string  `gorm:"not null;size:100"`
+	AvatarURL       string  `gorm:"size:255"`
+	Email           *string `gorm:"unique;size:255"` // 可选，唯一
+	EmailVerifiedAt *time.Time
+	Phone           *string `gorm:"unique;size:32"` // 可选，唯一
+	PhoneVerifiedAt *time.Time
+	TOTPSecret      string     `gorm:"column:totp_secret;size:64"` // 两步验证密钥，启用前为待确认的密钥
+	TOTPEnabledAt   *time.Time `gorm:"column:totp_enabled_at"`
+	TOTPLastStep    int64      `gorm:"column:totp_last_step;not null;default:0"` // 最近一次使用的时间步，防止验证码重放
+	Role            UserRole   `gorm:"size:50;not null;default:'user'"`
+	Status          UserStatus `gorm:"type:enum('active','banned');not null;default:'active'"`
+	BanReason       string     `gorm:"size:255"`
+	BannedUntil     *time.Time // 为空表示永久封禁
+	CreatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	DeletedAt       gorm.DeletedAt
+	Guides          []TravelGuide
+	Tags            []Tag `gorm:"many2many:user_tags;joinForeignKey:user_id;joinReferences:tag_id"`
 }
 
 type TravelGuide struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement"`
-	Title       string    `gorm:"not null;size:255"`
-	Content     string    `gorm:"not null;type:text"`
-	Images      string    `gorm:"type:text"`
-	UserID      uint      `gorm:"not null"`
-	User        User      `gorm:"foreignKey:UserID"`
-	PublishedAt time.Time `gorm:"not null"`
-	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
-	DeletedAt   *time.Time
-	Tags        []Tag `gorm:"many2many:guide_tags;joinForeignKey:guide_id;joinReferences:tag_id"`
+	ID          uint        `gorm:"primaryKey;autoIncrement"`
+	Title       string      `gorm:"not null;size:255"`
+	Content     string      `gorm:"not null;type:text"`
+	UserID      uint        `gorm:"not null"`
+	User        User        `gorm:"foreignKey:UserID"`
+	Status      GuideStatus `gorm:"type:enum('draft','scheduled','published','archived');not null;default:'published'"`
+	PublishedAt time.Time   `gorm:"not null"`
+	CreatedAt   time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time   `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	DeletedAt   gorm.DeletedAt
+	Tags        []Tag        `gorm:"many2many:guide_tags;joinForeignKey:guide_id;joinReferences:tag_id"`
+	Images      []GuideImage `gorm:"foreignKey:GuideID"`
 }
 
 type Tag struct {
@@ -54,7 +81,7 @@ type Tag struct {
 	Name      string    `gorm:"unique;not null;size:50"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
-	DeletedAt *time.Time
+	DeletedAt gorm.DeletedAt
 	Guides    []TravelGuide `gorm:"many2many:guide_tags;joinForeignKey:tag_id;joinReferences:guide_id"`
 }
 
@@ -64,3 +91,226 @@ type UserTag struct {
 	TagID     uint      `gorm:"primaryKey;column:tag_id"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;column:created_at"`
 }
+
+// GuideRevision 攻略每次保存时的快照，Images 和 Tags 为 JSON 数组
+type GuideRevision struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	GuideID   uint      `gorm:"not null;uniqueIndex:idx_guide_version"`
+	Version   int       `gorm:"not null;uniqueIndex:idx_guide_version"`
+	Title     string    `gorm:"not null;size:255"`
+	Content   string    `gorm:"not null;type:text"`
+	Images    string    `gorm:"type:text"`
+	Tags      string    `gorm:"type:text"`
+	EditorID  uint      `gorm:"not null"`
+	Editor    User      `gorm:"foreignKey:EditorID"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// GuideLike represents a user liking a travel guide
+type GuideLike struct {
+	UserID    uint      `gorm:"primaryKey;column:user_id"`
+	GuideID   uint      `gorm:"primaryKey;column:guide_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;column:created_at"`
+}
+
+// GuideFavorite represents a user bookmarking a travel guide
+type GuideFavorite struct {
+	UserID    uint      `gorm:"primaryKey;column:user_id"`
+	GuideID   uint      `gorm:"primaryKey;column:guide_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;column:created_at"`
+}
+
+// Comment 攻略评论，ParentID 为空表示顶层评论，否则为对顶层评论的回复（只支持一层）
+type Comment struct {
+	ID            uint  `gorm:"primaryKey;autoIncrement"`
+	GuideID       uint  `gorm:"not null;index"`
+	UserID        uint  `gorm:"not null"`
+	User          User  `gorm:"foreignKey:UserID"`
+	ParentID      *uint `gorm:"index"`
+	ReplyToUserID *uint
+	ReplyToUser   *User     `gorm:"foreignKey:ReplyToUserID"`
+	Content       string    `gorm:"not null;type:text"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	DeletedAt     gorm.DeletedAt
+}
+
+// UserFollow represents FollowerID following FolloweeID
+type UserFollow struct {
+	FollowerID uint      `gorm:"primaryKey;column:follower_id"`
+	FolloweeID uint      `gorm:"primaryKey;column:followee_id"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP;column:created_at"`
+}
+
+// UserSession 一次登录产生的会话，访问令牌通过 sid 关联到会话，会话撤销后令牌立即失效
+type UserSession struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement"`
+	UserID            uint      `gorm:"not null;index"`
+	RefreshTokenHash  string    `gorm:"not null;size:64;uniqueIndex"`
+	PreviousTokenHash string    `gorm:"size:64;index"` // 上一次轮换前的刷新令牌，用于发现令牌被重复使用
+	UserAgent         string    `gorm:"size:255"`
+	IP                string    `gorm:"size:64"`
+	ExpiresAt         time.Time `gorm:"not null"`
+	RevokedAt         *time.Time
+	CreatedAt         time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+}
+
+// TokenPurpose 验证令牌的用途
+type TokenPurpose string
+
+const (
+	TokenPurposeEmailVerify   TokenPurpose = "email_verify"   //验证邮箱
+	TokenPurposePhoneVerify   TokenPurpose = "phone_verify"   //验证手机号
+	TokenPurposePasswordReset TokenPurpose = "password_reset" //重置密码
+)
+
+// VerificationToken 邮箱/手机验证和重置密码使用的一次性令牌，只保存哈希
+type VerificationToken struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement"`
+	UserID    uint         `gorm:"not null;index"`
+	Purpose   TokenPurpose `gorm:"type:enum('email_verify','phone_verify','password_reset');not null"`
+	Target    string       `gorm:"not null;size:255"` // 发送到的邮箱或手机号，验证时需与用户当前资料一致
+	TokenHash string       `gorm:"not null;size:64;index"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// UserRecoveryCode 两步验证的恢复码，每个只能使用一次，只保存哈希
+type UserRecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;size:64"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Image 上传的图片，Variants 为各尺寸缩略图的 JSON 数组
+type Image struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	UserID      uint      `gorm:"not null;index"`
+	ObjectKey   string    `gorm:"not null;size:255;uniqueIndex"`
+	URL         string    `gorm:"not null;size:512;index"`
+	Format      string    `gorm:"not null;size:10"`
+	Width       int       `gorm:"not null"`
+	Height      int       `gorm:"not null"`
+	Bytes       int64     `gorm:"not null"`
+	Placeholder string    `gorm:"size:7"` // 主色调 #rrggbb
+	Variants    string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// ImageVariant 图片的一个尺寸
+type ImageVariant struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// VariantList 解析 Variants 字段
+func (i *Image) VariantList() []ImageVariant {
+	var variants []ImageVariant
+	_ = json.Unmarshal([]byte(i.Variants), &variants)
+	return variants
+}
+
+// PendingUpload 已签发直传地址、等待客户端确认的上传，确认后删除
+// 超过 ExpiresAt 仍未确认的记录由后台任务连同对象一起清理
+type PendingUpload struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	UserID      uint      `gorm:"not null;index"`
+	ObjectKey   string    `gorm:"not null;size:255;uniqueIndex"`
+	ContentType string    `gorm:"not null;size:50"`
+	Size        int64     `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// GuideImage 攻略中的一张图片，按 Position 排序
+// 通过本站上传的图片 ImageID 指向 images 表，外部图片只有 URL
+type GuideImage struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	GuideID   uint      `gorm:"not null;index"`
+	ImageID   *uint     `gorm:"index"`
+	Image     *Image    `gorm:"foreignKey:ImageID"`
+	Position  int       `gorm:"not null"`
+	URL       string    `gorm:"not null;size:512"`
+	Caption   string    `gorm:"size:500"`
+	Width     int       `gorm:"not null;default:0"`
+	Height    int       `gorm:"not null;default:0"`
+	OwnerID   uint      `gorm:"not null"` // 上传者，外部图片为添加它的用户
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// APIKey 用户创建的个人访问密钥，用于脚本调用接口，只保存哈希
+type APIKey struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"not null;size:100"`
+	Prefix     string `gorm:"not null;size:16"` // 密钥开头几位，便于用户辨认
+	KeyHash    string `gorm:"not null;size:64;uniqueIndex"`
+	Scopes     string `gorm:"not null;size:255"` // 空格分隔的授权范围
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time // 为空表示永不过期
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 默认会被命名为 a_p_i_keys
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope 判断密钥是否包含某个授权范围
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range strings.Fields(k.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// UserIdentity 用户绑定的外部登录身份，同一提供方的同一账号只能绑定一个用户
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index"`
+	Provider  string    `gorm:"not null;size:50;uniqueIndex:idx_provider_subject"`
+	Subject   string    `gorm:"not null;size:255;uniqueIndex:idx_provider_subject"`
+	Email     string    `gorm:"size:255"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+}
+
+// Role 角色定义，Name 对应 users.role
+type Role struct {
+	ID          uint             `gorm:"primaryKey;autoIncrement"`
+	Name        UserRole         `gorm:"unique;not null;size:50"`
+	Description string           `gorm:"size:255"`
+	IsSystem    bool             `gorm:"not null;default:false"` // 内置角色不能删除
+	Permissions []RolePermission `gorm:"foreignKey:RoleID"`
+	CreatedAt   time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time        `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+}
+
+// RolePermission 角色拥有的一项权限
+type RolePermission struct {
+	RoleID     uint   `gorm:"primaryKey"`
+	Permission string `gorm:"primaryKey;size:100"`
+}
+
+// TwoFactorEnabled 是否已启用两步验证
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// IsBanned 判断用户在 now 时刻是否处于封禁状态，封禁到期后视为正常
+func (u *User) IsBanned(now time.Time) bool {
+	if u.Status != StatusBanned {
+		return false
+	}
+	return u.BannedUntil == nil || u.BannedUntil.After(now)
+}
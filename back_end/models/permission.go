@@ -0,0 +1,83 @@
+package models
+
+// 权限名称，按 资源.操作[.范围] 命名
+const (
+	PermissionAll = "*" // 拥有全部权限，仅授予管理员
+
+	PermGuideViewHidden    = "guide.view.hidden"    // 查看未发布、已删除的攻略
+	PermGuideEditAny       = "guide.edit.any"       // 编辑任意攻略
+	PermGuideDeleteAny     = "guide.delete.any"     // 删除任意攻略
+	PermGuideRestore       = "guide.restore"        // 恢复已删除的攻略
+	PermCommentDeleteAny   = "comment.delete.any"   // 删除任意评论
+	PermTagDelete          = "tag.delete"           // 删除和恢复标签
+	PermTagMerge           = "tag.merge"            // 合并标签
+	PermUserView           = "user.view"            // 查看用户列表
+	PermUserBan            = "user.ban"             // 封禁和解封用户
+	PermUserDelete         = "user.delete"          // 删除和恢复用户
+	PermUserRoleAssign     = "user.role.assign"     // 修改用户角色
+	PermUserSessionRevoke  = "user.session.revoke"  // 撤销用户的登录会话
+	PermLoginLockoutManage = "login.lockout.manage" // 查看和解除登录锁定
+	PermRoleManage         = "role.manage"          // 管理角色定义
+)
+
+// API 密钥的授权范围，接口在路由中声明接受哪个范围，未声明的接口不接受 API 密钥
+const (
+	ScopeGuidesRead     = "guides:read"     // 读取攻略，包括自己的草稿
+	ScopeGuidesWrite    = "guides:write"    // 创建、修改、删除攻略和上传图片
+	ScopeCommentsWrite  = "comments:write"  // 发表和删除评论
+	ScopeReactionsWrite = "reactions:write" // 点赞和收藏
+	ScopeProfileRead    = "profile:read"    // 读取自己的资料
+)
+
+// PermissionInfo 权限说明，用于角色管理界面展示
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions 系统支持的全部权限
+var Permissions = []PermissionInfo{
+	{PermGuideViewHidden, "查看未发布、已删除的攻略"},
+	{PermGuideEditAny, "编辑任意攻略"},
+	{PermGuideDeleteAny, "删除任意攻略"},
+	{PermGuideRestore, "恢复已删除的攻略"},
+	{PermCommentDeleteAny, "删除任意评论"},
+	{PermTagDelete, "删除和恢复标签"},
+	{PermTagMerge, "合并标签"},
+	{PermUserView, "查看用户列表"},
+	{PermUserBan, "封禁和解封用户"},
+	{PermUserDelete, "删除和恢复用户"},
+	{PermUserRoleAssign, "修改用户角色"},
+	{PermUserSessionRevoke, "撤销用户的登录会话"},
+	{PermLoginLockoutManage, "查看和解除登录锁定"},
+	{PermRoleManage, "管理角色定义"},
+}
+
+// APIKeyScopes 系统支持的全部 API 密钥授权范围
+var APIKeyScopes = []PermissionInfo{
+	{ScopeGuidesRead, "读取攻略，包括自己的草稿"},
+	{ScopeGuidesWrite, "创建、修改、删除攻略和上传图片"},
+	{ScopeCommentsWrite, "发表和删除评论"},
+	{ScopeReactionsWrite, "点赞和收藏"},
+	{ScopeProfileRead, "读取自己的资料"},
+}
+
+// IsKnownScope 判断授权范围是否在 APIKeyScopes 中
+func IsKnownScope(name string) bool {
+	for _, s := range APIKeyScopes {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnownPermission 判断权限名称是否在 Permissions 中
+func IsKnownPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -3,43 +3,145 @@ package routes
 import (
 	"travel_guide/controllers"
 	"travel_guide/middleware"
+	"travel_guide/models"
+	"travel_guide/utils/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
+func SetupRoutes(r *gin.Engine, db *gorm.DB, store storage.Storage) {
 	// User routes
 	userController := controllers.NewUserController(db)
 	r.POST("/api/register", userController.CreateUser)
 	r.POST("/api/login", userController.Login)
-	r.GET("/api/users", middleware.AuthMiddleware(), middleware.AdminMiddleware(db), userController.GetUsers)
-	r.PUT("/api/users/:id/status", middleware.AuthMiddleware(), middleware.AdminMiddleware(db), userController.UpdateUserStatus)
+	r.POST("/api/login/2fa", userController.LoginTwoFactor)
+	r.POST("/api/token/refresh", userController.RefreshToken)
+	r.POST("/api/logout", middleware.AuthMiddleware(db), userController.Logout)
+	r.GET("/api/me", middleware.AuthMiddleware(db, models.ScopeProfileRead), userController.GetCurrentUser)
+	r.PATCH("/api/me", middleware.AuthMiddleware(db), userController.UpdateCurrentUser)
+	r.DELETE("/api/me", middleware.AuthMiddleware(db), userController.DeleteCurrentUser)
+	r.PUT("/api/me/password", middleware.AuthMiddleware(db), userController.ChangePassword)
+	r.GET("/api/users", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermUserView), userController.GetUsers)
+	r.GET("/api/users/:id", middleware.OptionalAuthMiddleware(db), userController.GetUserProfile)
+	r.PUT("/api/users/:id/status", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermUserBan), userController.UpdateUserStatus)
+	r.PUT("/api/users/:id/role", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermUserRoleAssign), userController.UpdateUserRole)
+	r.DELETE("/api/users/:id", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermUserDelete), userController.DeleteUser)
+	r.PUT("/api/users/:id/restore", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermUserDelete), userController.RestoreUser)
+	r.POST("/api/users/:id/sessions/revoke", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermUserSessionRevoke), userController.RevokeUserSessions)
+	r.GET("/api/login/lockouts", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermLoginLockoutManage), userController.GetLoginLockouts)
+	r.DELETE("/api/login/lockouts/:key", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermLoginLockoutManage), userController.ClearLoginLockout)
+
+	// Account routes
+	accountController := controllers.NewAccountController(db)
+	r.PUT("/api/me/contact", middleware.AuthMiddleware(db), accountController.UpdateContact)
+	r.POST("/api/me/email/verification", middleware.AuthMiddleware(db), accountController.SendEmailVerification)
+	r.POST("/api/email/verify", accountController.VerifyEmail)
+	r.POST("/api/me/phone/verification", middleware.AuthMiddleware(db), accountController.SendPhoneVerification)
+	r.POST("/api/me/phone/verify", middleware.AuthMiddleware(db), accountController.VerifyPhone)
+	r.POST("/api/password/forgot", accountController.ForgotPassword)
+	r.POST("/api/password/reset", accountController.ResetPassword)
+
+	// Two-factor routes
+	twoFactorController := controllers.NewTwoFactorController(db)
+	r.GET("/api/me/2fa", middleware.AuthMiddleware(db), twoFactorController.GetTwoFactorStatus)
+	r.POST("/api/me/2fa/setup", middleware.AuthMiddleware(db), twoFactorController.SetupTwoFactor)
+	r.POST("/api/me/2fa/enable", middleware.AuthMiddleware(db), twoFactorController.EnableTwoFactor)
+	r.POST("/api/me/2fa/disable", middleware.AuthMiddleware(db), twoFactorController.DisableTwoFactor)
+	r.POST("/api/me/2fa/recovery-codes", middleware.AuthMiddleware(db), twoFactorController.RegenerateRecoveryCodes)
+
+	// OAuth routes
+	oauthController := controllers.NewOAuthController(db)
+	r.GET("/api/oauth/providers", oauthController.GetOAuthProviders)
+	r.GET("/api/oauth/:provider/authorize", middleware.OptionalAuthMiddleware(db), oauthController.Authorize)
+	r.POST("/api/oauth/:provider/callback", middleware.OptionalAuthMiddleware(db), oauthController.Callback)
+	r.GET("/api/me/identities", middleware.AuthMiddleware(db), oauthController.GetMyIdentities)
+	r.DELETE("/api/me/identities/:provider", middleware.AuthMiddleware(db), oauthController.UnlinkIdentity)
+
+	// API key routes，密钥只能在登录会话中管理
+	apiKeyController := controllers.NewAPIKeyController(db)
+	r.GET("/api/me/api-keys", middleware.AuthMiddleware(db), apiKeyController.GetMyAPIKeys)
+	r.GET("/api/me/api-keys/scopes", middleware.AuthMiddleware(db), apiKeyController.GetAPIKeyScopes)
+	r.POST("/api/me/api-keys", middleware.AuthMiddleware(db), apiKeyController.CreateAPIKey)
+	r.DELETE("/api/me/api-keys/:id", middleware.AuthMiddleware(db), apiKeyController.RevokeAPIKey)
+
+	// Follow routes
+	followController := controllers.NewFollowController(db)
+	r.POST("/api/users/:id/follow", middleware.AuthMiddleware(db), followController.FollowUser)
+	r.DELETE("/api/users/:id/follow", middleware.AuthMiddleware(db), followController.UnfollowUser)
+	r.GET("/api/users/:id/followers", followController.GetFollowers)
+	r.GET("/api/users/:id/following", followController.GetFollowing)
 
 	// Guide routes
 	guideController := controllers.NewGuideController(db)
+	r.GET("/api/users/:id/guides", middleware.OptionalAuthMiddleware(db, models.ScopeGuidesRead), guideController.GetUserGuides)
 	guideRoutes := r.Group("/api/guides")
 	{
-		guideRoutes.POST("", middleware.AuthMiddleware(), guideController.CreateGuide)
-		guideRoutes.GET("", guideController.GetGuides)
-		guideRoutes.GET("/:id", guideController.GetGuideDetail)
+		guideRoutes.POST("", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.CreateGuide)
+		guideRoutes.GET("", middleware.OptionalAuthMiddleware(db, models.ScopeGuidesRead), guideController.GetGuides)
+		guideRoutes.GET("/mine", middleware.AuthMiddleware(db, models.ScopeGuidesRead), guideController.GetMyGuides)
+		guideRoutes.GET("/favorites", middleware.AuthMiddleware(db, models.ScopeGuidesRead), guideController.GetMyFavorites)
+		guideRoutes.GET("/following", middleware.AuthMiddleware(db, models.ScopeGuidesRead), guideController.GetFollowingFeed)
+		guideRoutes.GET("/:id", middleware.OptionalAuthMiddleware(db, models.ScopeGuidesRead), guideController.GetGuideDetail)
+		guideRoutes.PUT("/:id", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.UpdateGuide)
+		guideRoutes.PATCH("/:id", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.PatchGuide)
+		guideRoutes.DELETE("/:id", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.DeleteGuide)
+		guideRoutes.PUT("/:id/restore", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermGuideRestore), guideController.RestoreGuide)
+		guideRoutes.PUT("/:id/images/order", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.ReorderGuideImages)
+		guideRoutes.PATCH("/:id/images/:image_id", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.UpdateGuideImage)
+		guideRoutes.GET("/:id/revisions", middleware.AuthMiddleware(db, models.ScopeGuidesRead), guideController.GetGuideRevisions)
+		guideRoutes.GET("/:id/revisions/diff", middleware.AuthMiddleware(db, models.ScopeGuidesRead), guideController.DiffGuideRevisions)
+		guideRoutes.POST("/:id/revisions/:revision_id/rollback", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), guideController.RollbackGuideRevision)
+		guideRoutes.POST("/:id/like", middleware.AuthMiddleware(db, models.ScopeReactionsWrite), guideController.ToggleLike)
+		guideRoutes.POST("/:id/favorite", middleware.AuthMiddleware(db, models.ScopeReactionsWrite), guideController.ToggleFavorite)
 		guideRoutes.GET("/suggestions", guideController.GetSearchSuggestions)
-		guideRoutes.GET("/search", middleware.OptionalAuthMiddleware(db), guideController.SearchGuides)
-		guideRoutes.GET("/recommendations", middleware.AuthMiddleware(), guideController.GetUserRecommendations)
+		guideRoutes.GET("/search", middleware.OptionalAuthMiddleware(db, models.ScopeGuidesRead), guideController.SearchGuides)
+		guideRoutes.GET("/recommendations", middleware.AuthMiddleware(db, models.ScopeGuidesRead), guideController.GetUserRecommendations)
+	}
+
+	// Comment routes
+	commentController := controllers.NewCommentController(db)
+	commentRoutes := r.Group("/api/guides/:id/comments")
+	{
+		commentRoutes.GET("", commentController.GetComments)
+		commentRoutes.POST("", middleware.AuthMiddleware(db, models.ScopeCommentsWrite), commentController.CreateComment)
+		commentRoutes.GET("/:comment_id/replies", commentController.GetCommentReplies)
+		commentRoutes.DELETE("/:comment_id", middleware.AuthMiddleware(db, models.ScopeCommentsWrite), commentController.DeleteComment)
 	}
 
 	// Tag routes
 	tagController := controllers.NewTagController(db)
 	tagRoutes := r.Group("/api/tags")
 	{
-		tagRoutes.GET("", tagController.GetAllTags)
+		tagRoutes.GET("", middleware.OptionalAuthMiddleware(db), tagController.GetAllTags)
 		tagRoutes.GET("/related", tagController.GetRelatedTags)
+		tagRoutes.DELETE("/:id", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermTagDelete), tagController.DeleteTag)
+		tagRoutes.PUT("/:id/restore", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermTagDelete), tagController.RestoreTag)
+		tagRoutes.POST("/:id/merge", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermTagMerge), tagController.MergeTag)
+	}
+
+	// Role routes
+	roleController := controllers.NewRoleController(db)
+	roleRoutes := r.Group("/api/roles", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermRoleManage))
+	{
+		roleRoutes.GET("", roleController.GetRoles)
+		roleRoutes.POST("", roleController.CreateRole)
+		roleRoutes.PUT("/:name", roleController.UpdateRole)
+		roleRoutes.DELETE("/:name", roleController.DeleteRole)
 	}
+	r.GET("/api/permissions", middleware.AuthMiddleware(db), middleware.RequirePermission(db, models.PermRoleManage), roleController.GetPermissions)
 
 	// Upload routes
-	uploadController := controllers.NewUploadController()
+	uploadController := controllers.NewUploadController(db, store)
 	uploadRoutes := r.Group("/api/upload")
 	{
-		uploadRoutes.POST("/image", middleware.AuthMiddleware(), uploadController.UploadImage)
+		uploadRoutes.POST("/image", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), uploadController.UploadImage)
+		uploadRoutes.POST("/presign", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), uploadController.PresignUpload)
+		uploadRoutes.POST("/confirm", middleware.AuthMiddleware(db, models.ScopeGuidesWrite), uploadController.ConfirmUpload)
+	}
+
+	// 本地存储的文件由静态路由提供访问
+	if local, ok := store.(*storage.LocalStorage); ok {
+		r.Static(local.URLPrefix(), local.Root())
 	}
 }
@@ -0,0 +1,171 @@
+package seed
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"travel_guide/config"
+	"travel_guide/models"
+	"travel_guide/utils/avatar"
+	"travel_guide/utils/logger"
+	"travel_guide/utils/validate"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+// ErrAdminExists 数据库中已经有管理员，不再重复创建
+var ErrAdminExists = errors.New("an admin user already exists")
+
+// AdminCredentials 首个管理员的账号信息
+type AdminCredentials struct {
+	Username string
+	Password string
+	Email    string
+}
+
+// HasAdmin 判断数据库中是否已有未删除的管理员
+func HasAdmin(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateAdmin 创建首个管理员，已有管理员时返回 ErrAdminExists
+func CreateAdmin(db *gorm.DB, cred AdminCredentials) (*models.User, error) {
+	exists, err := HasAdmin(db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAdminExists
+	}
+
+	// 保留用户名只限制注册，管理员可以使用 admin 等名称
+	if msg := validate.Username(cred.Username, nil); msg != "" {
+		return nil, errors.New(msg)
+	}
+	if msg := validate.Password(cred.Password, config.AppConfig.UserConfig.PasswordPolicy()); msg != "" {
+		return nil, errors.New(msg)
+	}
+	if cred.Email != "" {
+		if msg := validate.Email(cred.Email); msg != "" {
+			return nil, errors.New(msg)
+		}
+	}
+
+	var count int64
+	db.Unscoped().Model(&models.User{}).Where("username = ?", cred.Username).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("username %s is already taken", cred.Username)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(cred.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Username:  cred.Username,
+		Password:  string(hashedPassword),
+		Nickname:  cred.Username,
+		AvatarURL: avatar.Default(cred.Username),
+		Role:      models.RoleAdmin,
+		Status:    models.StatusActive,
+	}
+	if cred.Email != "" {
+		user.Email = &cred.Email
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	logger.InfoLogger.Printf("已创建首个管理员 %s (ID %d)", user.Username, user.ID)
+	return &user, nil
+}
+
+// BootstrapAdmin 启动时检查管理员，没有管理员时使用 ADMIN_USERNAME、ADMIN_PASSWORD 创建
+// 未配置时只给出提示，不会使用默认密码
+func BootstrapAdmin(db *gorm.DB, cfg config.SeedConfig) error {
+	exists, err := HasAdmin(db)
+	if err != nil || exists {
+		return err
+	}
+
+	if cfg.AdminUsername == "" || cfg.AdminPassword == "" {
+		logger.InfoLogger.Printf("数据库中还没有管理员，请设置 ADMIN_USERNAME 和 ADMIN_PASSWORD 后重启，或运行 `go run . bootstrap-admin`")
+		return nil
+	}
+
+	_, err = CreateAdmin(db, AdminCredentials{
+		Username: cfg.AdminUsername,
+		Password: cfg.AdminPassword,
+		Email:    cfg.AdminEmail,
+	})
+	return err
+}
+
+// PromptAdmin 在命令行中交互式创建首个管理员，只能在还没有管理员时使用
+func PromptAdmin(db *gorm.DB, in io.Reader, out io.Writer) error {
+	exists, err := HasAdmin(db)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAdminExists
+	}
+
+	reader := bufio.NewReader(in)
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(out, prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	// 从终端输入密码时不回显，输入来自管道或文件时按普通行读取
+	askPassword := ask
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		askPassword = func(prompt string) (string, error) {
+			fmt.Fprint(out, prompt)
+			password, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(out)
+			return string(password), err
+		}
+	}
+
+	var cred AdminCredentials
+	if cred.Username, err = ask("管理员用户名: "); err != nil {
+		return err
+	}
+	if cred.Email, err = ask("管理员邮箱（可留空）: "); err != nil {
+		return err
+	}
+	cred.Username = strings.TrimSpace(cred.Username)
+	cred.Email = strings.TrimSpace(cred.Email)
+	if cred.Password, err = askPassword("管理员密码: "); err != nil {
+		return err
+	}
+	confirm, err := askPassword("再次输入密码: ")
+	if err != nil {
+		return err
+	}
+	if confirm != cred.Password {
+		return errors.New("两次输入的密码不一致")
+	}
+
+	user, err := CreateAdmin(db, cred)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "管理员 %s 创建成功\n", user.Username)
+	return nil
+}
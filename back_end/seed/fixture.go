@@ -0,0 +1,152 @@
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"travel_guide/config"
+	"travel_guide/models"
+	"travel_guide/utils/avatar"
+	"travel_guide/utils/logger"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fixture 演示数据文件的内容
+type Fixture struct {
+	Tags  []string      `json:"tags"`
+	Users []FixtureUser `json:"users"`
+}
+
+// FixtureUser 演示用户，密码以明文写在文件中，导入时加密
+type FixtureUser struct {
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	Nickname  string   `json:"nickname"`
+	AvatarURL string   `json:"avatar_url"`
+	Role      string   `json:"role"`
+	Tags      []string `json:"tags"` // 用户偏好标签
+}
+
+// LoadFixture 读取 JSON 格式的演示数据文件
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+	return &fixture, nil
+}
+
+// FixturePath 返回当前环境要导入的演示数据文件
+func FixturePath(cfg config.SeedConfig) string {
+	if cfg.FixtureFile != "" {
+		return cfg.FixtureFile
+	}
+	return filepath.Join(cfg.FixtureDir, cfg.Env+".json")
+}
+
+// Run 在开启 SEED_ENABLED 时导入当前环境的演示数据，默认不做任何事
+func Run(db *gorm.DB, cfg config.SeedConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	path := FixturePath(cfg)
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return err
+	}
+	if err := Apply(db, fixture); err != nil {
+		return fmt.Errorf("failed to apply fixture %s: %v", path, err)
+	}
+
+	logger.InfoLogger.Printf("已导入演示数据 %s：%d 个标签，%d 个用户", path, len(fixture.Tags), len(fixture.Users))
+	return nil
+}
+
+// Apply 导入演示数据，已存在的标签和用户会被跳过，可以重复执行
+func Apply(db *gorm.DB, fixture *Fixture) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range fixture.Tags {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Tag{Name: name}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, fu := range fixture.Users {
+			if err := applyUser(tx, fu); err != nil {
+				return fmt.Errorf("user %s: %v", fu.Username, err)
+			}
+		}
+		return nil
+	})
+}
+
+func applyUser(tx *gorm.DB, fu FixtureUser) error {
+	if fu.Username == "" || fu.Password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+
+	role := models.UserRole(fu.Role)
+	if role == "" {
+		role = models.RoleUser
+	}
+	// 管理员只能通过 bootstrap 创建，演示数据里不应该有已知密码的管理员
+	if role == models.RoleAdmin {
+		return fmt.Errorf("fixtures cannot create admin users")
+	}
+	var roleCount int64
+	tx.Model(&models.Role{}).Where("name = ?", role).Count(&roleCount)
+	if roleCount == 0 {
+		return fmt.Errorf("role %s does not exist", role)
+	}
+
+	var user models.User
+	err := tx.Unscoped().Where("username = ?", fu.Username).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(fu.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		nickname := fu.Nickname
+		if nickname == "" {
+			nickname = fu.Username
+		}
+		avatarURL := fu.AvatarURL
+		if avatarURL == "" {
+			avatarURL = avatar.Default(nickname)
+		}
+		user = models.User{
+			Username:  fu.Username,
+			Password:  string(hashedPassword),
+			Nickname:  nickname,
+			AvatarURL: avatarURL,
+			Role:      role,
+			Status:    models.StatusActive,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	for _, name := range fu.Tags {
+		var tag models.Tag
+		if err := tx.Where("name = ?", name).First(&tag).Error; err != nil {
+			return fmt.Errorf("tag %s not found", name)
+		}
+		if err := tx.Exec("INSERT IGNORE INTO user_tags (user_id, tag_id) VALUES (?, ?)", user.ID, tag.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
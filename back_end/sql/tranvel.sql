@@ -10,8 +10,17 @@ CREATE TABLE IF NOT EXISTS users (
     password VARCHAR(255) NOT NULL,
     nickname VARCHAR(100) NOT NULL,
     avatar_url VARCHAR(255),
-    role ENUM('admin', 'user') NOT NULL DEFAULT 'user' COMMENT '用户角色：admin-管理员，user-普通用户',
+    email VARCHAR(255) UNIQUE COMMENT '邮箱，可选',
+    email_verified_at TIMESTAMP NULL COMMENT '邮箱验证时间，为空表示未验证',
+    phone VARCHAR(32) UNIQUE COMMENT '手机号，可选',
+    phone_verified_at TIMESTAMP NULL COMMENT '手机号验证时间，为空表示未验证',
+    totp_secret VARCHAR(64) COMMENT '两步验证密钥（base32）',
+    totp_enabled_at TIMESTAMP NULL COMMENT '两步验证启用时间，为空表示未启用',
+    totp_last_step BIGINT NOT NULL DEFAULT 0 COMMENT '最近一次使用的验证码时间步，防止重放',
+    role VARCHAR(50) NOT NULL DEFAULT 'user' COMMENT '用户角色，对应 roles.name',
     status ENUM('active', 'banned') NOT NULL DEFAULT 'active' COMMENT '用户状态：active-正常，banned-封禁',
+    ban_reason VARCHAR(255) COMMENT '封禁原因',
+    banned_until TIMESTAMP NULL COMMENT '封禁到期时间，为空表示永久封禁',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP NULL,
@@ -34,8 +43,8 @@ CREATE TABLE IF NOT EXISTS travel_guides (
     id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
     content TEXT NOT NULL,
-    images TEXT,
     user_id BIGINT UNSIGNED NOT NULL,
+    status ENUM('draft', 'scheduled', 'published', 'archived') NOT NULL DEFAULT 'published' COMMENT '攻略状态：draft-草稿，scheduled-定时发布，published-已发布，archived-已归档',
     published_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -43,7 +52,8 @@ CREATE TABLE IF NOT EXISTS travel_guides (
     FOREIGN KEY (user_id) REFERENCES users(id),
     FULLTEXT INDEX idx_ft_title_content (title, content),
     INDEX idx_user_id (user_id),
-    INDEX idx_published_at (published_at)
+    INDEX idx_published_at (published_at),
+    INDEX idx_status_published_at (status, published_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 -- 攻略-标签关联表
@@ -68,6 +78,241 @@ CREATE TABLE IF NOT EXISTS user_tags (
     INDEX idx_tag_id (tag_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 攻略历史版本表
+CREATE TABLE IF NOT EXISTS guide_revisions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    guide_id BIGINT UNSIGNED NOT NULL,
+    version INT NOT NULL COMMENT '版本号，同一攻略内递增',
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    images TEXT COMMENT '图片URL的JSON数组',
+    tags TEXT COMMENT '标签名的JSON数组',
+    editor_id BIGINT UNSIGNED NOT NULL COMMENT '保存该版本的用户',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+    FOREIGN KEY (editor_id) REFERENCES users(id),
+    UNIQUE INDEX idx_guide_version (guide_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 攻略点赞表
+CREATE TABLE IF NOT EXISTS guide_likes (
+    user_id BIGINT UNSIGNED NOT NULL,
+    guide_id BIGINT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, guide_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+    INDEX idx_guide_id (guide_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 攻略收藏表
+CREATE TABLE IF NOT EXISTS guide_favorites (
+    user_id BIGINT UNSIGNED NOT NULL,
+    guide_id BIGINT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, guide_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+    INDEX idx_guide_id (guide_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 攻略评论表
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    guide_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    parent_id BIGINT UNSIGNED NULL COMMENT '所属顶层评论，为空表示顶层评论',
+    reply_to_user_id BIGINT UNSIGNED NULL COMMENT '被回复的用户',
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (parent_id) REFERENCES comments(id),
+    INDEX idx_guide_parent (guide_id, parent_id),
+    INDEX idx_parent_id (parent_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 用户关注表
+CREATE TABLE IF NOT EXISTS user_follows (
+    follower_id BIGINT UNSIGNED NOT NULL COMMENT '关注者',
+    followee_id BIGINT UNSIGNED NOT NULL COMMENT '被关注者',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id),
+    FOREIGN KEY (followee_id) REFERENCES users(id),
+    INDEX idx_followee_id (followee_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 登录会话表
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    refresh_token_hash CHAR(64) NOT NULL UNIQUE COMMENT '当前刷新令牌的SHA-256',
+    previous_token_hash CHAR(64) COMMENT '上一个刷新令牌的SHA-256，被重复使用时撤销会话',
+    user_agent VARCHAR(255),
+    ip VARCHAR(64),
+    expires_at TIMESTAMP NOT NULL COMMENT '刷新令牌过期时间',
+    revoked_at TIMESTAMP NULL COMMENT '撤销时间，不为空表示会话已失效',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_previous_token_hash (previous_token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 角色表
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    is_system BOOLEAN NOT NULL DEFAULT FALSE COMMENT '内置角色不能删除',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 角色权限表
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT UNSIGNED NOT NULL,
+    permission VARCHAR(100) NOT NULL COMMENT '权限名称，* 表示全部权限',
+    PRIMARY KEY (role_id, permission),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 外部登录身份表
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    provider VARCHAR(50) NOT NULL COMMENT '登录方式名称，对应 OAUTH_PROVIDERS',
+    subject VARCHAR(255) NOT NULL COMMENT '用户在提供方的唯一标识（sub）',
+    email VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE KEY idx_provider_subject (provider, subject),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 验证令牌表（邮箱/手机验证、重置密码）
+CREATE TABLE IF NOT EXISTS verification_tokens (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    purpose ENUM('email_verify', 'phone_verify', 'password_reset') NOT NULL COMMENT '用途',
+    target VARCHAR(255) NOT NULL COMMENT '发送到的邮箱或手机号',
+    token_hash CHAR(64) NOT NULL COMMENT '令牌或验证码的SHA-256',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL COMMENT '使用时间，不为空表示已失效',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX idx_token_hash (token_hash),
+    INDEX idx_user_purpose (user_id, purpose)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 两步验证恢复码表
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    code_hash CHAR(64) NOT NULL COMMENT '恢复码的SHA-256',
+    used_at TIMESTAMP NULL COMMENT '使用时间，不为空表示已失效',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 上传图片表，variants 为各尺寸缩略图的 JSON 数组
+CREATE TABLE IF NOT EXISTS images (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    object_key VARCHAR(255) NOT NULL UNIQUE COMMENT '原图在存储中的对象名',
+    url VARCHAR(512) NOT NULL COMMENT '原图访问地址',
+    format VARCHAR(10) NOT NULL COMMENT 'jpeg/png/gif/webp',
+    width INT NOT NULL,
+    height INT NOT NULL,
+    bytes BIGINT NOT NULL,
+    placeholder VARCHAR(7) COMMENT '主色调，图片加载前的占位色',
+    variants TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_url (url)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 攻略图片表，按 position 排序；通过本站上传的图片 image_id 指向 images 表
+CREATE TABLE IF NOT EXISTS guide_images (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    guide_id BIGINT UNSIGNED NOT NULL,
+    image_id BIGINT UNSIGNED NULL COMMENT '外部图片为空',
+    position INT NOT NULL COMMENT '在攻略中的顺序，从 0 开始',
+    url VARCHAR(512) NOT NULL COMMENT '原图访问地址',
+    caption VARCHAR(500) COMMENT '图片说明',
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    owner_id BIGINT UNSIGNED NOT NULL COMMENT '上传者，外部图片为添加它的用户',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (guide_id) REFERENCES travel_guides(id),
+    FOREIGN KEY (image_id) REFERENCES images(id),
+    FOREIGN KEY (owner_id) REFERENCES users(id),
+    INDEX idx_guide_position (guide_id, position),
+    INDEX idx_image_id (image_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 直传上传记录，签发直传地址时创建，客户端确认上传后删除
+CREATE TABLE IF NOT EXISTS pending_uploads (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    object_key VARCHAR(255) NOT NULL UNIQUE COMMENT '签发给客户端的对象名',
+    content_type VARCHAR(50) NOT NULL COMMENT '客户端声明的图片类型',
+    size BIGINT NOT NULL COMMENT '客户端声明的文件大小',
+    expires_at TIMESTAMP NOT NULL COMMENT '超过该时间未确认的上传会被清理',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 个人 API 密钥表
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL COMMENT '密钥开头几位，便于辨认',
+    key_hash CHAR(64) NOT NULL UNIQUE COMMENT '密钥的SHA-256',
+    scopes VARCHAR(255) NOT NULL COMMENT '空格分隔的授权范围',
+    last_used_at TIMESTAMP NULL COMMENT '最近一次使用时间',
+    expires_at TIMESTAMP NULL COMMENT '过期时间，为空表示永不过期',
+    revoked_at TIMESTAMP NULL COMMENT '撤销时间，不为空表示已失效',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 插入内置角色
+INSERT IGNORE INTO roles (name, description, is_system) VALUES
+('admin', '管理员，拥有全部权限', TRUE),
+('moderator', '版主，负责内容审核和用户管理', FALSE),
+('editor', '编辑，负责攻略和标签整理', FALSE),
+('user', '普通用户', TRUE);
+
+-- 插入内置角色的默认权限
+INSERT IGNORE INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (
+    SELECT 'admin' AS role, '*' AS permission UNION ALL
+    SELECT 'moderator', 'guide.view.hidden' UNION ALL
+    SELECT 'moderator', 'guide.delete.any' UNION ALL
+    SELECT 'moderator', 'guide.restore' UNION ALL
+    SELECT 'moderator', 'comment.delete.any' UNION ALL
+    SELECT 'moderator', 'user.view' UNION ALL
+    SELECT 'moderator', 'user.ban' UNION ALL
+    SELECT 'moderator', 'user.session.revoke' UNION ALL
+    SELECT 'editor', 'guide.view.hidden' UNION ALL
+    SELECT 'editor', 'guide.edit.any' UNION ALL
+    SELECT 'editor', 'tag.delete' UNION ALL
+    SELECT 'editor', 'tag.merge'
+) p ON p.role = r.name;
+
 -- 插入初始标签
 INSERT IGNORE INTO tags (name) VALUES 
 -- 旅行类型
@@ -80,26 +325,8 @@ INSERT IGNORE INTO tags (name) VALUES
 ('森林徒步'),
 ('草原牧场');
 
--- 插入模拟用户数据
-INSERT IGNORE INTO users (username, password, nickname, avatar_url, role) VALUES 
-('admin', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', 'admin', 'https://api.dicebear.com/7.x/initials/svg?seed=admin', 'admin'),
-('travel_lover', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '旅行爱好者', 'https://api.dicebear.com/7.x/initials/svg?seed=旅行爱好者', 'user'),
-('food_explorer', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '美食探索家', 'https://api.dicebear.com/7.x/initials/svg?seed=美食探索家', 'user'),
-('nature_seeker', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '自然追寻者', 'https://api.dicebear.com/7.x/initials/svg?seed=自然追寻者', 'user'),
-('city_wanderer', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '城市漫游者', 'https://api.dicebear.com/7.x/initials/svg?seed=城市漫游者', 'user'),
-('adventure_seeker', '$2a$10$CPmq3FF9E9Vf622Tt5bSmOAKLATwZraADO3haRCcMGcHeU7lfJakC', '冒险家', 'https://api.dicebear.com/7.x/initials/svg?seed=冒险家', 'user');
-
--- 插入用户标签关联数据
-INSERT IGNORE INTO user_tags (user_id, tag_id) 
-SELECT u.id, t.id 
-FROM users u 
-CROSS JOIN tags t 
-WHERE 
-    (u.username = 'travel_lover' AND t.name IN ('自然风光', '城市探索', '海岛度假')) OR
-    (u.username = 'food_explorer' AND t.name IN ('城市探索', '乡村田园')) OR
-    (u.username = 'nature_seeker' AND t.name IN ('自然风光', '高原雪山', '森林徒步', '草原牧场')) OR
-    (u.username = 'city_wanderer' AND t.name IN ('城市探索', '海岛度假')) OR
-    (u.username = 'adventure_seeker' AND t.name IN ('高原雪山', '沙漠戈壁', '森林徒步'));
+-- 演示用户不再写入数据库脚本，开发环境通过 SEED_ENABLED=true 导入 seed/fixtures/<APP_ENV>.json，
+-- 首个管理员通过 ADMIN_USERNAME / ADMIN_PASSWORD 或 go run . bootstrap-admin 创建
 
 -- 插入攻略标签关联数据
 INSERT IGNORE INTO guide_tags (guide_id, tag_id)
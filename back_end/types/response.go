@@ -14,6 +14,13 @@ type PaginationResponse struct {
 	HasMore bool        `json:"has_more"`
 }
 
+// 游标分页响应结构
+type CursorResponse struct {
+	List       interface{} `json:"list"`
+	NextCursor uint        `json:"next_cursor"`
+	HasMore    bool        `json:"has_more"`
+}
+
 // 成功响应
 func SuccessResponse(data interface{}, message string) Response {
 	if message == "" {
@@ -35,25 +42,83 @@ func ErrorResponse(code int, message string) Response {
 	}
 }
 
+// 带错误码的错误响应，前端可以根据 error_code 区分失败原因
+func CodedErrorResponse(errorCode, message string) Response {
+	return Response{
+		Code:    1,
+		Message: message,
+		Data:    map[string]interface{}{"error_code": errorCode},
+	}
+}
+
+// 字段校验失败响应，errors 为按字段列出的错误
+func ValidationErrorResponse(errors interface{}) Response {
+	return Response{
+		Code:    1,
+		Message: "参数校验失败",
+		Data:    map[string]interface{}{"errors": errors},
+	}
+}
+
+// ImageVariantResponse 图片的一个尺寸
+type ImageVariantResponse struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// ImageResponse 图片及其缩略图，外部图片只有 URL
+// Variants 固定包含 thumbnail、medium、large，原图不够大时指向原图
+// 上传接口返回时 ID 为上传图片的ID；攻略中的图片 ID 为攻略图片的ID，上传图片的ID 在 ImageID 中
+type ImageResponse struct {
+	ID          uint                            `json:"id,omitempty"`
+	ImageID     uint                            `json:"image_id,omitempty"`
+	URL         string                          `json:"url"`
+	Width       int                             `json:"width,omitempty"`
+	Height      int                             `json:"height,omitempty"`
+	Placeholder string                          `json:"placeholder,omitempty"`
+	Caption     string                          `json:"caption,omitempty"`
+	Variants    map[string]ImageVariantResponse `json:"variants"`
+}
+
+// PresignedUploadResponse 直传地址，客户端用 Method 和 Headers 把文件上传到 URL 后，
+// 再用 Key 调用确认接口
+type PresignedUploadResponse struct {
+	Key       string            `json:"key"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt int64             `json:"expires_at"`
+}
+
 // 各种响应数据结构
 type GuideResponse struct {
-	ID          uint          `json:"id"`
-	Title       string        `json:"title"`
-	Content     string        `json:"content"`
-	Images      []string      `json:"images"`
-	UserID      uint          `json:"user_id"`
-	User        UserResponse  `json:"user"`
-	PublishedAt int64         `json:"published_at"`
-	Tags        []TagResponse `json:"tags"`
+	ID          uint            `json:"id"`
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Images      []ImageResponse `json:"images"`
+	UserID      uint            `json:"user_id"`
+	User        UserResponse    `json:"user"`
+	Status      string          `json:"status"`
+	PublishedAt int64           `json:"published_at"`
+	Tags        []TagResponse   `json:"tags"`
+	DeletedAt   int64           `json:"deleted_at,omitempty"`
+
+	LikeCount     int64 `json:"like_count"`
+	FavoriteCount int64 `json:"favorite_count"`
+	Liked         bool  `json:"liked"`     // 当前用户是否已点赞，未登录时为 false
+	Favorited     bool  `json:"favorited"` // 当前用户是否已收藏，未登录时为 false
+	CommentCount  int64 `json:"comment_count"`
 }
 
 type CreateGuideResponse struct {
-	ID          uint          `json:"id"`
-	Title       string        `json:"title"`
-	Content     string        `json:"content"`
-	Images      []string      `json:"images"`
-	PublishedAt int64         `json:"published_at"`
-	Tags        []TagResponse `json:"tags"`
+	ID          uint            `json:"id"`
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Images      []ImageResponse `json:"images"`
+	Status      string          `json:"status"`
+	PublishedAt int64           `json:"published_at"`
+	Tags        []TagResponse   `json:"tags"`
 }
 
 type UserResponse struct {
@@ -63,7 +128,76 @@ type UserResponse struct {
 	AvatarURL string `json:"avatar_url"`
 }
 
+type UserProfileResponse struct {
+	UserResponse
+	GuideCount     int64         `json:"guide_count"`
+	FollowerCount  int64         `json:"follower_count"`
+	FollowingCount int64         `json:"following_count"`
+	PreferredTags  []TagResponse `json:"preferred_tags"`
+	Following      bool          `json:"following"` // 当前用户是否已关注，未登录时为 false
+}
+
 type TagResponse struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	DeletedAt int64  `json:"deleted_at,omitempty"`
+}
+
+type GuideRevisionResponse struct {
+	ID        uint         `json:"id"`
+	GuideID   uint         `json:"guide_id"`
+	Version   int          `json:"version"`
+	Title     string       `json:"title"`
+	Content   string       `json:"content"`
+	Images    []string     `json:"images"`
+	Tags      []string     `json:"tags"`
+	Editor    UserResponse `json:"editor"`
+	CreatedAt int64        `json:"created_at"`
+}
+
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type GuideRevisionDiffResponse struct {
+	FromVersion   int          `json:"from_version"`
+	ToVersion     int          `json:"to_version"`
+	Title         *FieldChange `json:"title"`
+	Content       interface{}  `json:"content"`
+	ImagesAdded   []string     `json:"images_added"`
+	ImagesRemoved []string     `json:"images_removed"`
+	TagsAdded     []string     `json:"tags_added"`
+	TagsRemoved   []string     `json:"tags_removed"`
+}
+
+type CommentResponse struct {
+	ID          uint              `json:"id"`
+	GuideID     uint              `json:"guide_id"`
+	ParentID    uint              `json:"parent_id,omitempty"`
+	User        UserResponse      `json:"user"`
+	ReplyToUser *UserResponse     `json:"reply_to_user,omitempty"`
+	Content     string            `json:"content"`
+	CreatedAt   int64             `json:"created_at"`
+	ReplyCount  int64             `json:"reply_count"`
+	Replies     []CommentResponse `json:"replies,omitempty"`
+}
+
+type RoleResponse struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	IsSystem    bool     `json:"is_system"`
+	Permissions []string `json:"permissions"`
+	UserCount   int64    `json:"user_count"`
+}
+
+type APIKeyResponse struct {
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"`
+	CreatedAt  int64    `json:"created_at"`
 }
@@ -0,0 +1,15 @@
+package avatar
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Default 使用昵称的第一个字符生成 DiceBear 默认头像地址，昵称为空时使用问号
+func Default(nickname string) string {
+	runes := []rune(strings.TrimSpace(nickname))
+	if len(runes) == 0 {
+		runes = []rune("?")
+	}
+	return "https://api.dicebear.com/7.x/initials/svg?seed=" + url.QueryEscape(string(runes[0]))
+}
@@ -0,0 +1,162 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+)
+
+// Op 表示一行文本的变化类型
+type Op string
+
+const (
+	OpEqual  Op = "equal"  //未变化
+	OpInsert Op = "insert" //新增
+	OpDelete Op = "delete" //删除
+)
+
+// Line 一行差异结果
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxEdits 逐行比较时允许的最大增删行数，超过时返回 ErrTooManyChanges，
+// 避免大段改写的内容占用过多内存和时间
+const MaxEdits = 1000
+
+// ErrTooManyChanges 两段文本的差异超过 MaxEdits
+var ErrTooManyChanges = errors.New("diff: too many changes")
+
+// Lines 按行比较两段文本，去掉相同的开头和结尾后使用 Myers 算法生成最短的差异
+func Lines(a, b string) ([]Line, error) {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+
+	prefix := 0
+	for prefix < len(aLines) && prefix < len(bLines) && aLines[prefix] == bLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(aLines)-prefix && suffix < len(bLines)-prefix &&
+		aLines[len(aLines)-1-suffix] == bLines[len(bLines)-1-suffix] {
+		suffix++
+	}
+
+	middle, err := myers(aLines[prefix:len(aLines)-suffix], bLines[prefix:len(bLines)-suffix])
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Line, 0, prefix+len(middle)+suffix)
+	for _, text := range aLines[:prefix] {
+		result = append(result, Line{Op: OpEqual, Text: text})
+	}
+	result = append(result, middle...)
+	for _, text := range aLines[len(aLines)-suffix:] {
+		result = append(result, Line{Op: OpEqual, Text: text})
+	}
+	return result, nil
+}
+
+// myers 使用 Myers O(ND) 算法计算最短编辑脚本，D 超过 MaxEdits 时放弃
+func myers(a, b []string) ([]Line, error) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > MaxEdits {
+		limit = MaxEdits
+	}
+
+	// v[off+k] 为第 k 条对角线上能到达的最远 x，trace[d] 保存第 d 步开始前 k ∈ [-d-1, d+1] 的值
+	off := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // 从上一条对角线向下移动，即插入一行
+			} else {
+				x = v[off+k-1] + 1 // 从下一条对角线向右移动，即删除一行
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace), nil
+			}
+		}
+	}
+	return nil, ErrTooManyChanges
+}
+
+// backtrack 从终点沿 trace 倒推出编辑脚本
+func backtrack(a, b []string, trace [][]int) []Line {
+	var result []Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && vd[k-1+d+1] < vd[k+1+d+1]) {
+			prevK = k + 1
+		}
+		prevX := vd[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			result = append(result, Line{Op: OpEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				result = append(result, Line{Op: OpInsert, Text: b[y-1]})
+			} else {
+				result = append(result, Line{Op: OpDelete, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// Strings 比较两个字符串集合，返回新增和删除的元素（保持原有顺序）
+func Strings(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+
+	added = []string{}
+	for _, s := range b {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	removed = []string{}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcsLength 朴素的最长公共子序列，用于验证 Myers 算法得到的是最短差异
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func randomText(r *rand.Rand, lines int) string {
+	words := []string{"a", "b", "c", "d"}
+	parts := make([]string, lines)
+	for i := range parts {
+		parts[i] = words[r.Intn(len(words))]
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestLinesMinimalAndReversible(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a, b := randomText(r, r.Intn(30)), randomText(r, r.Intn(30))
+		lines, err := Lines(a, b)
+		if err != nil {
+			t.Fatalf("Lines(%q, %q): %v", a, b, err)
+		}
+
+		var gotA, gotB []string
+		edits := 0
+		for _, line := range lines {
+			if line.Op != OpInsert {
+				gotA = append(gotA, line.Text)
+			}
+			if line.Op != OpDelete {
+				gotB = append(gotB, line.Text)
+			}
+			if line.Op != OpEqual {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "\n") != a || strings.Join(gotB, "\n") != b {
+			t.Fatalf("diff of %q and %q does not reproduce the inputs", a, b)
+		}
+
+		aLines, bLines := splitLines(a), splitLines(b)
+		if want := len(aLines) + len(bLines) - 2*lcsLength(aLines, bLines); edits != want {
+			t.Fatalf("diff of %q and %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestLinesTooManyChanges(t *testing.T) {
+	var a, b []string
+	for i := 0; i < MaxEdits; i++ {
+		a = append(a, "old")
+		b = append(b, "new")
+	}
+	if _, err := Lines(strings.Join(a, "\n"), strings.Join(b, "\n")); err != ErrTooManyChanges {
+		t.Fatalf("err = %v, want ErrTooManyChanges", err)
+	}
+
+	// 只改动中间一行的长文本不受限制
+	a[MaxEdits/2] = "changed"
+	lines, err := Lines(strings.Join(a, "\n")+"\nend", strings.Join(a[:MaxEdits/2], "\n")+"\nnew\n"+strings.Join(a[MaxEdits/2+1:], "\n")+"\nend")
+	if err != nil || len(lines) != MaxEdits+2 {
+		t.Fatalf("got %d lines, err %v", len(lines), err)
+	}
+}
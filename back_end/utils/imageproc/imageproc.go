@@ -0,0 +1,140 @@
+package imageproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器
+	_ "image/jpeg" // 注册 JPEG 解码器
+	_ "image/png"  // 注册 PNG 解码器
+	"strings"
+
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+// Format 按文件内容识别出的图片格式
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+)
+
+// ContentType 返回格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Ext 返回格式对应的文件扩展名
+func (f Format) Ext() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// FormatFromContentType 根据 MIME 类型返回支持的图片格式，用于校验客户端声明的类型
+func FormatFromContentType(contentType string) (Format, bool) {
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "image/jpeg", "image/jpg":
+		return FormatJPEG, true
+	case "image/png":
+		return FormatPNG, true
+	case "image/gif":
+		return FormatGIF, true
+	case "image/webp":
+		return FormatWebP, true
+	}
+	return "", false
+}
+
+// 校验失败的错误码，返回给前端用于区分失败原因
+const (
+	CodeTooLarge         = "image_too_large"
+	CodeUnsupported      = "image_unsupported_type"
+	CodeCorrupt          = "image_corrupt"
+	CodeTooManyPixels    = "image_too_many_pixels"
+	CodeInvalidDimension = "image_invalid_dimensions"
+)
+
+// Error 图片校验失败，Code 为固定的错误码，Message 为给用户看的说明
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Limits 图片大小限制，为 0 表示不限制
+type Limits struct {
+	MaxBytes  int64
+	MaxPixels int64
+}
+
+// Info 校验通过的图片信息
+type Info struct {
+	Format Format
+	Width  int
+	Height int
+	Image  image.Image // 完整解码的图片，Process 直接使用，不再重复解码
+}
+
+// Sniff 根据文件头的魔数识别图片格式，不是支持的图片时返回 false
+func Sniff(data []byte) (Format, bool) {
+	switch {
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		return FormatJPEG, true
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, true
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF, true
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP, true
+	}
+	return "", false
+}
+
+// Inspect 按内容校验图片：识别格式、检查大小和像素数，并完整解码一次确认文件没有损坏
+// 先读取尺寸再解码，避免解码超大图片耗尽内存
+func Inspect(data []byte, limits Limits) (*Info, error) {
+	if limits.MaxBytes > 0 && int64(len(data)) > limits.MaxBytes {
+		return nil, &Error{CodeTooLarge, fmt.Sprintf("图片不能超过 %s", formatBytes(limits.MaxBytes))}
+	}
+
+	format, ok := Sniff(data)
+	if !ok {
+		return nil, &Error{CodeUnsupported, "只支持 JPEG、PNG、GIF 和 WebP 格式的图片"}
+	}
+
+	cfg, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || Format(name) != format {
+		return nil, &Error{CodeCorrupt, "图片文件已损坏或无法识别"}
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, &Error{CodeInvalidDimension, "图片尺寸无效"}
+	}
+	if limits.MaxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > limits.MaxPixels {
+		return nil, &Error{CodeTooManyPixels, fmt.Sprintf("图片像素过多（%dx%d），最多 %d 像素", cfg.Width, cfg.Height, limits.MaxPixels)}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &Error{CodeCorrupt, "图片文件已损坏或无法识别"}
+	}
+
+	return &Info{Format: format, Width: cfg.Width, Height: cfg.Height, Image: img}, nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%d字节", n)
+}
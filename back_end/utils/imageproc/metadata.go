@@ -0,0 +1,239 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// errMalformed 文件结构不完整，无法安全地去除元数据
+var errMalformed = errors.New("imageproc: malformed image")
+
+// StripMetadata 去除 EXIF（包括 GPS 位置）、XMP、IPTC 和文本注释等元数据，不重新编码像素数据
+// JPEG 的方向信息会保留在一个只包含 Orientation 的最小 EXIF 中，避免图片显示方向出错
+func StripMetadata(data []byte, format Format) ([]byte, error) {
+	switch format {
+	case FormatJPEG:
+		return stripJPEG(data)
+	case FormatPNG:
+		return stripPNG(data)
+	case FormatWebP:
+		return stripWebP(data)
+	}
+	// GIF 没有 EXIF，原样返回
+	return data, nil
+}
+
+// stripJPEG 去掉 APP1（EXIF、XMP）、APP13（IPTC）和 COM 段，保留 JFIF、ICC 和 Adobe 段
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientation := 1
+	exifWritten := false
+
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, errMalformed
+		}
+		marker := data[pos+1]
+		// 段之间可能有 0xFF 填充字节
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		// SOS 之后是压缩数据，直接复制到结尾
+		if marker == 0xDA {
+			if !exifWritten && orientation != 1 {
+				out = append(out, minimalExif(orientation)...)
+			}
+			out = append(out, data[pos:]...)
+			return out, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errMalformed
+		}
+		segment := data[pos:end]
+		payload := data[pos+4 : end]
+
+		switch {
+		case marker == 0xE1:
+			if o, ok := exifOrientation(payload); ok {
+				orientation = o
+			}
+		case marker == 0xED, marker == 0xFE:
+			// IPTC 和注释
+		default:
+			// JFIF 之后紧跟方向信息，与常见相机输出的顺序一致
+			if marker != 0xE0 && !exifWritten && orientation != 1 {
+				out = append(out, minimalExif(orientation)...)
+				exifWritten = true
+			}
+			out = append(out, segment...)
+		}
+		pos = end
+	}
+}
+
+// Orientation 读取 JPEG 的 EXIF 方向，没有方向信息时返回 1
+func Orientation(data []byte) int {
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		if marker == 0xE1 {
+			if o, ok := exifOrientation(data[pos+4 : end]); ok {
+				return o
+			}
+		}
+		pos = end
+	}
+	return 1
+}
+
+// exifOrientation 从 APP1 的 EXIF 数据中读取 Orientation 标签
+func exifOrientation(payload []byte) (int, bool) {
+	if len(payload) < 14 || string(payload[:6]) != "Exif\x00\x00" {
+		return 0, false
+	}
+	tiff := payload[6:]
+
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value, true
+			}
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// minimalExif 生成只包含 Orientation 的 APP1 段
+func minimalExif(orientation int) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xE1, 0x00, 0x00})
+	b.WriteString("Exif\x00\x00")
+	b.WriteString("MM\x00*")
+	binary.Write(&b, binary.BigEndian, uint32(8))      // IFD0 偏移
+	binary.Write(&b, binary.BigEndian, uint16(1))      // 条目数
+	binary.Write(&b, binary.BigEndian, uint16(0x0112)) // Orientation
+	binary.Write(&b, binary.BigEndian, uint16(3))      // SHORT
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, uint16(orientation))
+	binary.Write(&b, binary.BigEndian, uint16(0))
+	binary.Write(&b, binary.BigEndian, uint32(0)) // 没有下一个 IFD
+
+	segment := b.Bytes()
+	binary.BigEndian.PutUint16(segment[2:4], uint16(len(segment)-2))
+	return segment
+}
+
+// stripPNG 去掉 eXIf 和文本块，块的 CRC 只覆盖自身，删除整块不影响其他块
+func stripPNG(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+
+	pos := 8
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+		switch string(data[pos+4 : pos+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
+
+// stripWebP 去掉 EXIF 和 XMP 块，并同步修改 VP8X 标志位和 RIFF 长度
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformed
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size%2
+		if end > len(data) {
+			// 最后一个块可能省略了填充字节
+			if pos+8+size == len(data) {
+				end = len(data)
+			} else {
+				return nil, errMalformed
+			}
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,382 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+const (
+	gpsMarker = "WGS-84"                       // GPSMapDatum 的值，出现在输出中说明 GPS 信息没有去掉
+	xmpMarker = "http://ns.adobe.com/xap/1.0/" // XMP 的命名空间
+	textValue = "Shot at home"                 // JPEG 注释和 PNG 文本块的内容
+)
+
+// 1x1 的无损 WebP（VP8L）
+var tinyWebP, _ = base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+// exifPayload 生成 APP1/eXIf/EXIF 使用的 EXIF 数据：IFD0 包含 Orientation 和指向 GPS IFD 的 GPSInfo
+func exifPayload(order binary.ByteOrder, orientation int) []byte {
+	var b bytes.Buffer
+	b.WriteString("Exif\x00\x00")
+	if order == binary.LittleEndian {
+		b.WriteString("II*\x00")
+	} else {
+		b.WriteString("MM\x00*")
+	}
+	binary.Write(&b, order, uint32(8)) // IFD0 偏移
+
+	// IFD0：2 个条目，从 TIFF 头偏移 8 开始，长度 2+2*12+4=30
+	gpsOffset := uint32(8 + 30)
+	binary.Write(&b, order, uint16(2))
+	binary.Write(&b, order, uint16(0x0112)) // Orientation
+	binary.Write(&b, order, uint16(3))      // SHORT
+	binary.Write(&b, order, uint32(1))
+	binary.Write(&b, order, uint16(orientation))
+	binary.Write(&b, order, uint16(0))
+	binary.Write(&b, order, uint16(0x8825)) // GPSInfo
+	binary.Write(&b, order, uint16(4))      // LONG
+	binary.Write(&b, order, uint32(1))
+	binary.Write(&b, order, gpsOffset)
+	binary.Write(&b, order, uint32(0))
+
+	// GPS IFD：GPSLatitudeRef 和 GPSMapDatum，后者的值放在 IFD 之后
+	datumOffset := gpsOffset + 2 + 2*12 + 4
+	binary.Write(&b, order, uint16(2))
+	binary.Write(&b, order, uint16(0x0001)) // GPSLatitudeRef
+	binary.Write(&b, order, uint16(2))      // ASCII
+	binary.Write(&b, order, uint32(2))
+	b.WriteString("N\x00\x00\x00")
+	binary.Write(&b, order, uint16(0x0012)) // GPSMapDatum
+	binary.Write(&b, order, uint16(2))
+	binary.Write(&b, order, uint32(len(gpsMarker)+1))
+	binary.Write(&b, order, datumOffset)
+	binary.Write(&b, order, uint32(0))
+	b.WriteString(gpsMarker + "\x00")
+	return b.Bytes()
+}
+
+// ifd0Tags 返回 EXIF 数据中 IFD0 的所有标签
+func ifd0Tags(t *testing.T, payload []byte) []uint16 {
+	t.Helper()
+	tiff := payload[6:]
+	var order binary.ByteOrder = binary.BigEndian
+	if string(tiff[:2]) == "II" {
+		order = binary.LittleEndian
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	tags := make([]uint16, 0, count)
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		tags = append(tags, order.Uint16(tiff[entry:entry+2]))
+	}
+	return tags
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegSegments 列出 SOS 之前的所有段
+func jpegSegments(t *testing.T, data []byte) map[byte][][]byte {
+	t.Helper()
+	segments := make(map[byte][][]byte)
+	pos := 2
+	for pos+4 <= len(data) && data[pos+1] != 0xDA {
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:pos+4]))
+		segments[data[pos+1]] = append(segments[data[pos+1]], data[pos+4:end])
+		pos = end
+	}
+	return segments
+}
+
+func encodeTestImage(t *testing.T, format Format) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.RGBA{R: 200, A: 255})
+		img.Set(x, 1, color.RGBA{B: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == FormatJPEG {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// jpegFixture 在 SOI 之后插入 JFIF、EXIF（含 GPS）、XMP、IPTC 和注释段
+func jpegFixture(t *testing.T, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+	encoded := encodeTestImage(t, FormatJPEG)
+	out := append([]byte(nil), encoded[:2]...)
+	out = append(out, jpegSegment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))...)
+	out = append(out, jpegSegment(0xE1, exifPayload(order, orientation))...)
+	out = append(out, jpegSegment(0xE1, []byte(xmpMarker+"\x00<x:xmpmeta/>"))...)
+	out = append(out, jpegSegment(0xED, []byte("Photoshop 3.0\x00"))...)
+	out = append(out, jpegSegment(0xFE, []byte(textValue))...)
+	return append(out, encoded[2:]...)
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[:4], uint32(len(data)))
+	copy(chunk[4:], kind)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngFixture 在 IHDR 之后插入 eXIf（含 GPS）、tEXt 和 XMP 的 iTXt 块
+func pngFixture(t *testing.T) []byte {
+	t.Helper()
+	encoded := encodeTestImage(t, FormatPNG)
+	ihdrEnd := 8 + 12 + 13
+	out := append([]byte(nil), encoded[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", exifPayload(binary.BigEndian, 6)[6:])...)
+	out = append(out, pngChunk("tEXt", []byte("Comment\x00"+textValue))...)
+	out = append(out, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"+xmpMarker))...)
+	return append(out, encoded[ihdrEnd:]...)
+}
+
+func webpChunk(fourCC string, data []byte) []byte {
+	chunk := append([]byte(fourCC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpFixture 把 tinyWebP 改写为扩展格式：VP8X 声明 EXIF 和 XMP，图像块之后附带 EXIF（含 GPS）和 XMP 块
+func webpFixture(t *testing.T) []byte {
+	t.Helper()
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04 // EXIF、XMP
+	// 画布宽高减一，各 24 位，1x1 时均为 0
+
+	out := []byte("RIFF\x00\x00\x00\x00WEBP")
+	out = append(out, webpChunk("VP8X", vp8x)...)
+	out = append(out, tinyWebP[12:]...)
+	out = append(out, webpChunk("EXIF", exifPayload(binary.LittleEndian, 6)[6:])...)
+	out = append(out, webpChunk("XMP ", []byte("<x:xmpmeta xmlns:xmp=\""+xmpMarker+"\"/>"))...)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
+
+func mustDecode(t *testing.T, data []byte, format Format) image.Image {
+	t.Helper()
+	img, name, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode %s: %v", format, err)
+	}
+	if Format(name) != format {
+		t.Fatalf("decoded as %s, want %s", name, format)
+	}
+	return img
+}
+
+func assertNoMetadata(t *testing.T, data []byte) {
+	t.Helper()
+	for _, marker := range []string{gpsMarker, xmpMarker, textValue} {
+		if bytes.Contains(data, []byte(marker)) {
+			t.Errorf("stripped output still contains %q", marker)
+		}
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		fixture := jpegFixture(t, order, 6)
+		want := mustDecode(t, fixture, FormatJPEG)
+		if got := Orientation(fixture); got != 6 {
+			t.Fatalf("%v: fixture orientation = %d, want 6", order, got)
+		}
+
+		out, err := StripMetadata(fixture, FormatJPEG)
+		if err != nil {
+			t.Fatalf("%v: StripMetadata: %v", order, err)
+		}
+		got := mustDecode(t, out, FormatJPEG)
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%v: bounds = %v, want %v", order, got.Bounds(), want.Bounds())
+		}
+		assertNoMetadata(t, out)
+		if got := Orientation(out); got != 6 {
+			t.Errorf("%v: orientation after strip = %d, want 6", order, got)
+		}
+
+		segments := jpegSegments(t, out)
+		if len(segments[0xE0]) != 1 {
+			t.Errorf("%v: JFIF segments = %d, want 1", order, len(segments[0xE0]))
+		}
+		if len(segments[0xED]) != 0 || len(segments[0xFE]) != 0 {
+			t.Errorf("%v: IPTC or comment segment was kept", order)
+		}
+		if len(segments[0xE1]) != 1 {
+			t.Fatalf("%v: APP1 segments = %d, want 1", order, len(segments[0xE1]))
+		}
+		tags := ifd0Tags(t, segments[0xE1][0])
+		if len(tags) != 1 || tags[0] != 0x0112 {
+			t.Errorf("%v: IFD0 tags = %#x, want only Orientation", order, tags)
+		}
+	}
+}
+
+func TestStripJPEGWithoutOrientation(t *testing.T) {
+	fixture := jpegFixture(t, binary.BigEndian, 1)
+	out, err := StripMetadata(fixture, FormatJPEG)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	mustDecode(t, out, FormatJPEG)
+	assertNoMetadata(t, out)
+	// 默认方向不需要写回 EXIF
+	if n := len(jpegSegments(t, out)[0xE1]); n != 0 {
+		t.Errorf("APP1 segments = %d, want 0", n)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	fixture := pngFixture(t)
+	want := mustDecode(t, fixture, FormatPNG)
+
+	out, err := StripMetadata(fixture, FormatPNG)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	got := mustDecode(t, out, FormatPNG)
+	if got.Bounds() != want.Bounds() || got.At(0, 1) != want.At(0, 1) {
+		t.Fatal("stripped PNG has different pixels")
+	}
+	assertNoMetadata(t, out)
+	for _, kind := range []string{"eXIf", "tEXt", "iTXt"} {
+		if bytes.Contains(out, []byte(kind)) {
+			t.Errorf("%s chunk was kept", kind)
+		}
+	}
+	if !bytes.Equal(out, encodeTestImage(t, FormatPNG)) {
+		t.Error("stripped PNG differs from the original encoding")
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+	fixture := webpFixture(t)
+	mustDecode(t, fixture, FormatWebP)
+
+	out, err := StripMetadata(fixture, FormatWebP)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	img := mustDecode(t, out, FormatWebP)
+	if img.Bounds().Dx() != 1 || img.Bounds().Dy() != 1 {
+		t.Fatalf("bounds = %v, want 1x1", img.Bounds())
+	}
+	assertNoMetadata(t, out)
+	if bytes.Contains(out, []byte("EXIF")) || bytes.Contains(out, []byte("XMP ")) {
+		t.Error("EXIF or XMP chunk was kept")
+	}
+	if size := int(binary.LittleEndian.Uint32(out[4:8])); size != len(out)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(out)-8)
+	}
+	// VP8X 不再声明 EXIF 和 XMP
+	if flags := out[20]; flags != 0 {
+		t.Errorf("VP8X flags = %#x, want 0", flags)
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		for o := 1; o <= 8; o++ {
+			if got, ok := exifOrientation(exifPayload(order, o)); !ok || got != o {
+				t.Errorf("%v: exifOrientation = %d, %v, want %d", order, got, ok, o)
+			}
+		}
+		if _, ok := exifOrientation(exifPayload(order, 9)); ok {
+			t.Errorf("%v: out-of-range orientation was accepted", order)
+		}
+	}
+
+	if o, ok := exifOrientation(minimalExif(3)[4:]); !ok || o != 3 {
+		t.Errorf("minimalExif round trip = %d, %v", o, ok)
+	}
+
+	payload := exifPayload(binary.BigEndian, 6)
+	for name, bad := range map[string][]byte{
+		"xmp":               []byte(xmpMarker + "\x00<x:xmpmeta/>"),
+		"short":             payload[:13],
+		"bad byte order":    append([]byte("Exif\x00\x00XX"), payload[8:]...),
+		"ifd past end":      append(append([]byte(nil), payload[:10]...), 0xFF, 0xFF, 0xFF, 0xF0),
+		"entries truncated": payload[:6+8+2+6],
+	} {
+		if _, ok := exifOrientation(bad); ok {
+			t.Errorf("%s: orientation was read from malformed EXIF", name)
+		}
+	}
+}
+
+// TestStripMalformed 截断或损坏的文件只能返回错误，不能 panic
+func TestStripMalformed(t *testing.T) {
+	fixtures := map[Format][]byte{
+		FormatJPEG: jpegFixture(t, binary.LittleEndian, 6),
+		FormatPNG:  pngFixture(t),
+		FormatWebP: webpFixture(t),
+	}
+	for format, fixture := range fixtures {
+		for n := 0; n < len(fixture); n++ {
+			StripMetadata(fixture[:n], format)
+			Orientation(fixture[:n])
+		}
+		// 逐个字节改成 0xFF，覆盖段长度、块长度和 IFD 偏移被篡改的情况
+		for i := range fixture {
+			corrupt := append([]byte(nil), fixture...)
+			corrupt[i] = 0xFF
+			StripMetadata(corrupt, format)
+			Orientation(corrupt)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"jpeg without soi":     []byte("not a jpeg"),
+		"jpeg zero length":     {0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x00, 0xFF, 0xDA},
+		"jpeg length past end": {0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 0x00},
+		"jpeg missing marker":  {0xFF, 0xD8, 0x00, 0xE1, 0x00, 0x02},
+	} {
+		if _, err := StripMetadata(data, FormatJPEG); err == nil {
+			t.Errorf("%s: malformed input was accepted", name)
+		}
+		Orientation(data)
+	}
+	for name, data := range map[string][]byte{
+		"signature only":   []byte("\x89PNG\r\n\x1a"),
+		"huge chunk":       append([]byte("\x89PNG\r\n\x1a\n\xff\xff\xff\xffIHDR"), make([]byte, 8)...),
+		"truncated header": []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIH"),
+	} {
+		if _, err := StripMetadata(data, FormatPNG); err == nil {
+			t.Errorf("png %s: malformed input was accepted", name)
+		}
+	}
+	for name, data := range map[string][]byte{
+		"header only":     []byte("RIFF\x04\x00\x00\x00WEB"),
+		"huge chunk":      []byte("RIFF\x10\x00\x00\x00WEBPEXIF\xff\xff\xff\xff"),
+		"truncated chunk": []byte("RIFF\x10\x00\x00\x00WEBPEXI"),
+	} {
+		if _, err := StripMetadata(data, FormatWebP); err == nil {
+			t.Errorf("webp %s: malformed input was accepted", name)
+		}
+	}
+}
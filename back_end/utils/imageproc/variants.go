@@ -0,0 +1,198 @@
+package imageproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// VariantSpec 缩放规格，按最长边等比缩小
+type VariantSpec struct {
+	Name    string
+	MaxSize int
+}
+
+// DefaultVariants 列表卡片、详情页和大图浏览使用的尺寸，按从大到小排列
+var DefaultVariants = []VariantSpec{
+	{Name: "large", MaxSize: 1600},
+	{Name: "medium", MaxSize: 800},
+	{Name: "thumbnail", MaxSize: 320},
+}
+
+// JPEGQuality 生成缩略图时的 JPEG 质量
+const JPEGQuality = 82
+
+// Variant 生成的一个尺寸
+type Variant struct {
+	Name   string
+	Format Format
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Processed 图片处理结果
+type Processed struct {
+	Width       int    // 按 EXIF 方向校正后的显示宽度
+	Height      int    // 按 EXIF 方向校正后的显示高度
+	Placeholder string // 主色调，#rrggbb，用于图片加载前的占位
+	Variants    []Variant
+}
+
+// Process 使用 Inspect 解码出的图片生成各尺寸的缩略图，原图小于某个尺寸时不生成该尺寸。
+// data 只用于读取 EXIF 方向，去除元数据后仍会保留
+func Process(data []byte, info *Info, specs []VariantSpec) (*Processed, error) {
+	src := info.Image
+	if src == nil {
+		return nil, fmt.Errorf("imageproc: image was not decoded by Inspect")
+	}
+
+	orientation := 1
+	if info.Format == FormatJPEG {
+		orientation = Orientation(data)
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	encodeFormat := FormatJPEG
+	if !isOpaque(src) {
+		encodeFormat = FormatPNG
+	}
+
+	result := &Processed{Width: width, Height: height}
+	// 依次从上一个尺寸缩小，避免每次都从大图开始缩放
+	current := src
+	var smallest image.Image = src
+	for _, spec := range specs {
+		w, h := fitSize(width, height, spec.MaxSize)
+		if w >= width && h >= height {
+			continue
+		}
+
+		// 缩放时按原始方向计算尺寸，缩放完再旋转，旋转的开销只与小图有关
+		sw, sh := w, h
+		if orientation >= 5 {
+			sw, sh = h, w
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, sw, sh))
+		xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), current, current.Bounds(), draw.Src, nil)
+		current = scaled
+		smallest = scaled
+
+		encoded, err := encode(applyOrientation(scaled, orientation), encodeFormat)
+		if err != nil {
+			return nil, fmt.Errorf("imageproc: encode %s: %v", spec.Name, err)
+		}
+		result.Variants = append(result.Variants, Variant{Name: spec.Name, Format: encodeFormat, Width: w, Height: h, Data: encoded})
+	}
+
+	result.Placeholder = dominantColor(smallest)
+	return result, nil
+}
+
+// fitSize 按最长边不超过 max 等比缩放
+func fitSize(width, height, max int) (int, int) {
+	if width <= max && height <= max {
+		return width, height
+	}
+	if width >= height {
+		h := height * max / width
+		if h < 1 {
+			h = 1
+		}
+		return max, h
+	}
+	w := width * max / height
+	if w < 1 {
+		w = 1
+	}
+	return w, max
+}
+
+func encode(img image.Image, format Format) ([]byte, error) {
+	var b bytes.Buffer
+	var err error
+	if format == FormatPNG {
+		err = png.Encode(&b, img)
+	} else {
+		err = jpeg.Encode(&b, img, &jpeg.Options{Quality: JPEGQuality})
+	}
+	return b.Bytes(), err
+}
+
+// isOpaque 判断图片是否没有透明像素，有透明像素时缩略图使用 PNG
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// dominantColor 计算平均色作为占位色
+func dominantColor(img image.Image) string {
+	bounds := img.Bounds()
+	// 大图只抽样，最多约 64×64 个点
+	stepX := bounds.Dx()/64 + 1
+	stepY := bounds.Dy()/64 + 1
+
+	var r, g, b, n uint64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r += uint64(cr >> 8)
+			g += uint64(cg >> 8)
+			b += uint64(cb >> 8)
+			n++
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r/n, g/n, b/n)
+}
+
+// applyOrientation 按 EXIF Orientation 旋转或翻转图片，1 表示无需处理
+func applyOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, w-1-x
+			}
+			si := img.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
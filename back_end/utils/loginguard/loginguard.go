@@ -0,0 +1,239 @@
+package loginguard
+
+import (
+	"sync"
+	"time"
+)
+
+// Record 某个维度（用户名或IP）的登录失败记录
+type Record struct {
+	Failures     int       `json:"failures"`
+	LastFailure  time.Time `json:"last_failure"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Locked       bool      `json:"locked"`       // 达到锁定阈值，区别于普通的退避等待
+	InFlight     int       `json:"in_flight"`    // 已通过 Begin 但还未结束的尝试
+	LastAttempt  time.Time `json:"last_attempt"` // 最近一次 Begin 的时间
+}
+
+// Store 登录失败记录的存储，默认使用内存实现，多实例部署时可替换为 Redis 等共享存储
+type Store interface {
+	Get(key string) (Record, bool)
+	Set(key string, record Record)
+	Delete(key string) bool
+	List() map[string]Record
+}
+
+// Policy 退避与锁定策略
+type Policy struct {
+	FreeAttempts     int           // 允许连续失败的次数，超过后开始指数退避
+	BaseDelay        time.Duration // 第一次退避的等待时间，之后每次翻倍
+	MaxDelay         time.Duration // 退避等待的上限
+	LockoutThreshold int           // 连续失败达到该次数后锁定
+	LockoutDuration  time.Duration // 锁定时长
+	ResetAfter       time.Duration // 超过该时间没有失败则清零
+}
+
+// UserPolicy 按用户名统计的默认策略
+func UserPolicy() Policy {
+	return Policy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  30 * time.Minute,
+		ResetAfter:       time.Hour,
+	}
+}
+
+// IPPolicy 按IP统计的默认策略，同一出口IP下可能有多个用户，阈值更宽松
+func IPPolicy() Policy {
+	return Policy{
+		FreeAttempts:     10,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 50,
+		LockoutDuration:  30 * time.Minute,
+		ResetAfter:       time.Hour,
+	}
+}
+
+// 超过该时间仍未结束的尝试视为调用方异常退出，不再占用名额
+const inFlightTimeout = time.Minute
+
+// Guard 按某一维度记录登录失败并计算是否需要等待
+type Guard struct {
+	store  Store
+	policy Policy
+	prefix string
+	mu     sync.Mutex
+}
+
+// NewGuard 创建 Guard，prefix 用于区分同一存储中的不同维度，如 "user:"、"ip:"
+func NewGuard(store Store, policy Policy, prefix string) *Guard {
+	return &Guard{store: store, policy: policy, prefix: prefix}
+}
+
+// Check 返回 id 当前是否被阻止登录，以及还需等待的时间
+func (g *Guard) Check(id string) (time.Duration, bool) {
+	record, ok := g.store.Get(g.prefix + id)
+	if !ok {
+		return 0, false
+	}
+
+	wait := time.Until(record.BlockedUntil)
+	if wait <= 0 {
+		return 0, false
+	}
+	return wait, true
+}
+
+// load 读取记录，清零过期的失败次数和超时未结束的尝试，调用方需持有 g.mu
+func (g *Guard) load(key string, now time.Time) Record {
+	record, _ := g.store.Get(key)
+	if now.Sub(record.LastFailure) > g.policy.ResetAfter {
+		record.Failures = 0
+		record.LastFailure = time.Time{}
+		record.BlockedUntil = time.Time{}
+		record.Locked = false
+	}
+	if now.Sub(record.LastAttempt) > inFlightTimeout {
+		record.InFlight = 0
+	}
+	return record
+}
+
+// Begin 在校验凭证之前预占一次尝试，处于退避或锁定期时返回还需等待的时间。
+// 检查和预占在同一把锁内完成：免费次数用完后同一 id 的尝试只能逐个进行，
+// 并发请求不会在失败被记录之前一起通过检查。未被阻止时调用方必须以 Fail、Done 或 Reset 结束本次尝试
+func (g *Guard) Begin(id string) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := g.prefix + id
+	now := time.Now()
+	record := g.load(key, now)
+	if wait := record.BlockedUntil.Sub(now); wait > 0 {
+		return wait, true
+	}
+	if record.InFlight > 0 && record.Failures+record.InFlight >= g.policy.FreeAttempts {
+		return g.policy.BaseDelay, true
+	}
+
+	record.InFlight++
+	record.LastAttempt = now
+	g.store.Set(key, record)
+	return 0, false
+}
+
+// Done 结束一次通过 Begin 预占、既未失败也不需要清除记录的尝试
+func (g *Guard) Done(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := g.prefix + id
+	record := g.load(key, time.Now())
+	if record.InFlight > 0 {
+		record.InFlight--
+	}
+	if record.Failures == 0 && record.InFlight == 0 {
+		g.store.Delete(key)
+		return
+	}
+	g.store.Set(key, record)
+}
+
+// Fail 记录一次登录失败，并按策略设置退避或锁定；通过 Begin 预占的尝试同时结束
+func (g *Guard) Fail(id string) Record {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := g.prefix + id
+	now := time.Now()
+	record := g.load(key, now)
+	if record.InFlight > 0 {
+		record.InFlight--
+	}
+
+	record.Failures++
+	record.LastFailure = now
+	switch {
+	case record.Failures >= g.policy.LockoutThreshold:
+		record.Locked = true
+		record.BlockedUntil = now.Add(g.policy.LockoutDuration)
+	case record.Failures > g.policy.FreeAttempts:
+		delay := g.policy.BaseDelay << uint(record.Failures-g.policy.FreeAttempts-1)
+		if delay <= 0 || delay > g.policy.MaxDelay {
+			delay = g.policy.MaxDelay
+		}
+		record.BlockedUntil = now.Add(delay)
+	}
+
+	g.store.Set(key, record)
+	return record
+}
+
+// Reset 登录成功后清除失败记录
+func (g *Guard) Reset(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.store.Delete(g.prefix + id)
+}
+
+// MemoryStore 基于内存的 Store 实现，重启后记录丢失
+type MemoryStore struct {
+	mu        sync.RWMutex
+	records   map[string]Record
+	maxAge    time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryStore 创建内存存储，超过 maxAge 未更新的记录会被定期清理
+func NewMemoryStore(maxAge time.Duration) *MemoryStore {
+	return &MemoryStore{
+		records:   make(map[string]Record),
+		maxAge:    maxAge,
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Get(key string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[key]
+	return record, ok
+}
+
+func (s *MemoryStore) Set(key string, record Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+
+	// 顺带清理过期记录，避免大量不同IP导致内存持续增长
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, r := range s.records {
+			if now.Sub(r.LastFailure) > s.maxAge && now.Sub(r.LastAttempt) > s.maxAge && now.After(r.BlockedUntil) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
+func (s *MemoryStore) Delete(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.records[key]
+	delete(s.records, key)
+	return ok
+}
+
+func (s *MemoryStore) List() map[string]Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[string]Record, len(s.records))
+	for k, r := range s.records {
+		result[k] = r
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package loginguard
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  time.Hour,
+		ResetAfter:       time.Hour,
+	}
+}
+
+func TestBeginLimitsConcurrentAttempts(t *testing.T) {
+	g := NewGuard(NewMemoryStore(time.Hour), testPolicy(), "user:")
+
+	// 并发的尝试在结束前一起到达，只有免费次数内的可以通过
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, blocked := g.Begin("alice"); !blocked {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 3 {
+		t.Fatalf("allowed = %d, want 3", allowed)
+	}
+
+	for i := 0; i < allowed; i++ {
+		g.Fail("alice")
+	}
+	// 免费次数用完后每次只允许一个尝试
+	if _, blocked := g.Begin("alice"); blocked {
+		t.Fatal("attempt after the free ones was blocked before any backoff")
+	}
+	if _, blocked := g.Begin("alice"); !blocked {
+		t.Fatal("second concurrent attempt after the free ones was allowed")
+	}
+	g.Fail("alice")
+	if wait, blocked := g.Begin("alice"); !blocked || wait <= 0 {
+		t.Fatalf("Begin after backoff started = %v, %v, want blocked", wait, blocked)
+	}
+}
+
+func TestDoneReleasesAttempt(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	g := NewGuard(store, testPolicy(), "ip:")
+
+	for i := 0; i < 10; i++ {
+		if _, blocked := g.Begin("1.2.3.4"); blocked {
+			t.Fatalf("attempt %d blocked", i)
+		}
+		g.Done("1.2.3.4")
+	}
+	if _, ok := store.Get("ip:1.2.3.4"); ok {
+		t.Fatal("record kept after every attempt finished without failures")
+	}
+}
+
+func TestResetClearsFailures(t *testing.T) {
+	g := NewGuard(NewMemoryStore(time.Hour), testPolicy(), "user:")
+
+	for i := 0; i < 4; i++ {
+		g.Begin("alice")
+		g.Fail("alice")
+	}
+	if _, blocked := g.Begin("alice"); !blocked {
+		t.Fatal("not blocked after exceeding the free attempts")
+	}
+	g.Reset("alice")
+	if _, blocked := g.Begin("alice"); blocked {
+		t.Fatal("still blocked after Reset")
+	}
+}
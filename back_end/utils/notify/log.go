@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier 把消息写入日志文件或标准输出，用于开发环境
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogNotifier 写入 w
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+// NewFileNotifier 以追加方式写入 path，path 为空时写入标准输出
+func NewFileNotifier(path string) (*LogNotifier, error) {
+	if path == "" {
+		return NewLogNotifier(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogNotifier(f), nil
+}
+
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintf(n.w, "[%s] %s to=%s subject=%q\n%s\n\n",
+		time.Now().Format("2006-01-02 15:04:05"), msg.Channel, msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// Channel 消息发送渠道
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Message 一条待发送的通知
+type Message struct {
+	Channel Channel
+	To      string // 邮箱地址或手机号
+	Subject string // 短信渠道忽略
+	Body    string
+}
+
+// Notifier 通知发送接口，验证码、重置密码等消息都通过它发送
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Router 按渠道把消息分发给不同的 Notifier，未配置的渠道返回错误
+type Router struct {
+	Email Notifier
+	SMS   Notifier
+}
+
+func (r *Router) Send(ctx context.Context, msg Message) error {
+	var n Notifier
+	switch msg.Channel {
+	case ChannelEmail:
+		n = r.Email
+	case ChannelSMS:
+		n = r.SMS
+	}
+	if n == nil {
+		return fmt.Errorf("notify: no notifier for channel %q", msg.Channel)
+	}
+	return n.Send(ctx, msg)
+}
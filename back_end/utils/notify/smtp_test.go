@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession 测试 SMTP 服务器收到的一封邮件
+type smtpSession struct {
+	Auth string // AUTH PLAIN 解码后的凭据
+	From string
+	To   []string
+	Data string
+}
+
+// startSMTPStub 启动只接受一次连接的最小 SMTP 服务器，不支持 STARTTLS
+func startSMTPStub(t *testing.T) (string, int, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		tp := textproto.NewConn(conn)
+		var s smtpSession
+		tp.PrintfLine("220 stub ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch {
+			case verb == "EHLO":
+				tp.PrintfLine("250-stub")
+				tp.PrintfLine("250 AUTH PLAIN")
+			case strings.HasPrefix(strings.ToUpper(line), "AUTH PLAIN "):
+				decoded, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+				s.Auth = string(decoded)
+				tp.PrintfLine("235 ok")
+			case verb == "MAIL":
+				s.From = line[len("MAIL FROM:"):]
+				tp.PrintfLine("250 ok")
+			case verb == "RCPT":
+				s.To = append(s.To, line[len("RCPT TO:"):])
+				tp.PrintfLine("250 ok")
+			case verb == "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				s.Data = string(data)
+				tp.PrintfLine("250 queued")
+			case verb == "QUIT":
+				tp.PrintfLine("221 bye")
+				sessions <- s
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return "127.0.0.1", addr.Port, sessions
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	host, port, sessions := startSMTPStub(t)
+	n := NewSMTPNotifier(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: "mailer",
+		Password: "secret",
+		From:     "noreply@example.com",
+	})
+
+	err := n.Send(context.Background(), Message{
+		Channel: ChannelEmail,
+		To:      "alice@example.com",
+		Subject: "重置密码",
+		Body:    "第一行\n第二行",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var s smtpSession
+	select {
+	case s = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp stub received no message")
+	}
+
+	if s.Auth != "\x00mailer\x00secret" {
+		t.Errorf("auth = %q", s.Auth)
+	}
+	if s.From != "<noreply@example.com>" {
+		t.Errorf("MAIL FROM = %q", s.From)
+	}
+	if len(s.To) != 1 || s.To[0] != "<alice@example.com>" {
+		t.Errorf("RCPT TO = %q", s.To)
+	}
+
+	headerText, body, ok := strings.Cut(s.Data, "\n\n")
+	if !ok {
+		t.Fatalf("message has no header/body separator: %q", s.Data)
+	}
+	header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(headerText + "\n\n"))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("parse headers: %v", err)
+	}
+	if got := header.Get("From"); got != "noreply@example.com" {
+		t.Errorf("From = %q", got)
+	}
+	if got := header.Get("To"); got != "alice@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil || subject != "重置密码" {
+		t.Errorf("Subject = %q (%v)", header.Get("Subject"), err)
+	}
+	if got := header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if _, err := time.Parse(time.RFC1123Z, header.Get("Date")); err != nil {
+		t.Errorf("Date = %q: %v", header.Get("Date"), err)
+	}
+	// ReadDotBytes 把 CRLF 转成了 LF
+	if body != "第一行\n第二行\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSMTPNotifierRejectsHeaderInjection(t *testing.T) {
+	n := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"})
+	err := n.Send(context.Background(), Message{
+		Channel: ChannelEmail,
+		To:      "alice@example.com\r\nBcc: mallory@example.com",
+		Subject: "hi",
+		Body:    "hi",
+	})
+	if err == nil {
+		t.Fatal("recipient with CRLF was accepted")
+	}
+}
@@ -0,0 +1,189 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig 标准 OpenID Connect 提供方的配置
+type OIDCConfig struct {
+	Name         string
+	Issuer       string // 用于拉取 {issuer}/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// OIDCProvider 通过 discovery 文档对接任意 OpenID Connect 提供方，
+// 用户信息从 userinfo 接口获取
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+}
+
+func NewOIDCProvider(config OIDCConfig) *OIDCProvider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &OIDCProvider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// discover 首次使用时拉取 discovery 文档，失败不缓存以便下次重试
+func (p *OIDCProvider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, wellKnown, "", &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %v", err)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("oidc discovery: incomplete document from %s", wellKnown)
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := p.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %v", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("oidc token exchange: empty access token")
+	}
+
+	var info struct {
+		Subject           string `json:"sub"`
+		Name              string `json:"name"`
+		Nickname          string `json:"nickname"`
+		PreferredUsername string `json:"preferred_username"`
+		Picture           string `json:"picture"`
+		Email             string `json:"email"`
+	}
+	if err := p.getJSON(ctx, doc.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+		return nil, fmt.Errorf("oidc userinfo: %v", err)
+	}
+	if info.Subject == "" {
+		return nil, fmt.Errorf("oidc userinfo: missing sub")
+	}
+
+	identity := &Identity{
+		Subject:   info.Subject,
+		Nickname:  info.Nickname,
+		AvatarURL: info.Picture,
+		Email:     info.Email,
+	}
+	for _, name := range []string{info.Name, info.PreferredUsername} {
+		if identity.Nickname == "" {
+			identity.Nickname = name
+		}
+	}
+	return identity, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, endpoint, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return p.doJSON(req, v)
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
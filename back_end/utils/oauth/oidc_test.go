@@ -0,0 +1,170 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubIssuer 最小的 OpenID Connect 提供方：授权时记下 code_challenge，换取令牌时校验 code_verifier
+type stubIssuer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	challenges map[string]string // code -> code_challenge
+	Subject    string
+	Nickname   string
+	Email      string
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	s := &stubIssuer{challenges: make(map[string]string), Subject: "subject-1", Nickname: "Stub User", Email: "stub@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"userinfo_endpoint":      s.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.ParseForm() != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		challenge, ok := s.challenges[r.PostForm.Get("code")]
+		delete(s.challenges, r.PostForm.Get("code"))
+		s.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]string{"access_token": "access-" + r.PostForm.Get("code"), "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]string{"sub": s.Subject, "name": s.Nickname, "email": s.Email})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Authorize 模拟用户在提供方完成授权，返回回调带回的 code
+func (s *stubIssuer) Authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorize url: %v", err)
+	}
+	if u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", u.Query().Get("code_challenge_method"))
+	}
+	code, err := RandomString(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.challenges[code] = u.Query().Get("code_challenge")
+	s.mu.Unlock()
+	return code
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCProviderPKCERoundTrip(t *testing.T) {
+	issuer := newStubIssuer(t)
+	provider := NewOIDCProvider(OIDCConfig{Name: "stub", Issuer: issuer.URL, ClientID: "client", RedirectURL: "http://app/callback"})
+	ctx := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := issuer.Authorize(t, authURL)
+
+	identity, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Subject != "subject-1" || identity.Nickname != "Stub User" || identity.Email != "stub@example.com" {
+		t.Fatalf("identity = %+v", identity)
+	}
+
+	// 授权码只能使用一次
+	if _, err := provider.Exchange(ctx, code, verifier); err == nil {
+		t.Fatal("reusing the authorization code succeeded")
+	}
+}
+
+func TestOIDCProviderRejectsWrongVerifier(t *testing.T) {
+	issuer := newStubIssuer(t)
+	provider := NewOIDCProvider(OIDCConfig{Name: "stub", Issuer: issuer.URL, ClientID: "client", RedirectURL: "http://app/callback"})
+	ctx := context.Background()
+
+	_, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := issuer.Authorize(t, authURL)
+
+	otherVerifier, _, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(ctx, code, otherVerifier); err == nil {
+		t.Fatal("Exchange with a mismatched code_verifier succeeded")
+	}
+}
+
+func TestMemoryStateStoreSingleUse(t *testing.T) {
+	store := NewMemoryStateStore()
+	store.Save("state", PendingLogin{Provider: "stub", CodeVerifier: "v", ExpiresAt: time.Now().Add(time.Minute)})
+
+	pending, ok := store.Take("state")
+	if !ok || pending.CodeVerifier != "v" {
+		t.Fatalf("Take = %+v, %v", pending, ok)
+	}
+	if _, ok := store.Take("state"); ok {
+		t.Fatal("state was accepted twice")
+	}
+	if _, ok := store.Take("unknown"); ok {
+		t.Fatal("unknown state was accepted")
+	}
+}
+
+func TestMemoryStateStoreExpired(t *testing.T) {
+	store := NewMemoryStateStore()
+	store.Save("expired", PendingLogin{Provider: "stub", ExpiresAt: time.Now().Add(-time.Second)})
+
+	if _, ok := store.Take("expired"); ok {
+		t.Fatal("expired state was accepted")
+	}
+}
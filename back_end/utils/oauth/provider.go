@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownProvider 未配置的登录方式
+var ErrUnknownProvider = errors.New("unknown oauth provider")
+
+// Identity 外部身份提供方返回的用户信息
+type Identity struct {
+	Subject   string // 用户在提供方的唯一标识
+	Nickname  string
+	AvatarURL string
+	Email     string
+}
+
+// Provider 外部身份提供方，使用授权码模式 + PKCE
+type Provider interface {
+	Name() string
+	// AuthCodeURL 生成跳转到提供方的授权地址
+	AuthCodeURL(ctx context.Context, state, codeChallenge string) (string, error)
+	// Exchange 用授权码和 PKCE verifier 换取令牌并获取用户信息
+	Exchange(ctx context.Context, code, codeVerifier string) (*Identity, error)
+}
+
+// Registry 按名称管理已配置的提供方
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register 注册提供方，同名时覆盖
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p.Name()] = p
+}
+
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names 返回已注册的提供方名称
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPKCE 生成 PKCE 的 code_verifier 和 S256 code_challenge
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString 生成 n 字节随机数的 base64url 编码
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"sync"
+	"time"
+)
+
+// PendingLogin 一次授权请求在服务端保存的信息，回调时按 state 取出
+type PendingLogin struct {
+	Provider     string
+	CodeVerifier string
+	LinkUserID   uint   // 不为 0 时表示已登录用户绑定新的外部身份
+	BindingHash  string // 发起授权的浏览器 Cookie 中随机值的哈希，回调必须来自同一浏览器
+	ExpiresAt    time.Time
+}
+
+// StateStore 保存授权请求，state 只能使用一次
+type StateStore interface {
+	Save(state string, pending PendingLogin)
+	Take(state string) (PendingLogin, bool)
+}
+
+// MemoryStateStore 基于内存的 StateStore 实现
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	pending map[string]PendingLogin
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{pending: make(map[string]PendingLogin)}
+}
+
+func (s *MemoryStateStore) Save(state string, pending PendingLogin) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 顺带清理过期的授权请求
+	now := time.Now()
+	for k, p := range s.pending {
+		if now.After(p.ExpiresAt) {
+			delete(s.pending, k)
+		}
+	}
+	s.pending[state] = pending
+}
+
+func (s *MemoryStateStore) Take(state string) (PendingLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, ok := s.pending[state]
+	if !ok {
+		return PendingLogin{}, false
+	}
+	delete(s.pending, state)
+	if time.Now().After(pending.ExpiresAt) {
+		return PendingLogin{}, false
+	}
+	return pending, true
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// OSSConfig 阿里云 OSS 配置
+type OSSConfig struct {
+	Endpoint        string
+	AccessKeyID     string
+	AccessKeySecret string
+	Bucket          string
+	PublicURL       string // 访问地址前缀，为空时使用 https://<bucket>.<endpoint>
+}
+
+// OSSStorage 阿里云 OSS 实现，当前版本的 SDK 不支持 context，请求超时由 SDK 自身控制
+type OSSStorage struct {
+	bucket    *oss.Bucket
+	publicURL string
+}
+
+// NewOSSStorage 创建 OSS 客户端，不会发起网络请求
+func NewOSSStorage(cfg OSSConfig) (*OSSStorage, error) {
+	client, err := oss.New(cfg.Endpoint, cfg.AccessKeyID, cfg.AccessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := client.Bucket(cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("https://%s.%s", cfg.Bucket, cfg.Endpoint)
+	}
+	return &OSSStorage{bucket: bucket, publicURL: publicURL}, nil
+}
+
+func (s *OSSStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	var options []oss.Option
+	if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	return s.bucket.PutObject(key, r, options...)
+}
+
+func (s *OSSStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.bucket.GetObject(key)
+	if serviceErr, ok := err.(oss.ServiceError); ok && serviceErr.StatusCode == 404 {
+		return nil, ErrNotFound
+	}
+	return body, err
+}
+
+func (s *OSSStorage) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	return s.bucket.DeleteObject(key)
+}
+
+// PresignPut OSS 的签名地址无法限制请求体大小，需要在确认上传时检查
+func (s *OSSStorage) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	signedURL, err := s.bucket.SignURL(key, oss.HTTPPut, int64(expires/time.Second), oss.ContentType(contentType))
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedRequest{
+		Method:    http.MethodPut,
+		URL:       signedURL,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+func (s *OSSStorage) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
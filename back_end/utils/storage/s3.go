@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config S3 兼容存储的配置，可用于 AWS S3、MinIO 等
+type S3Config struct {
+	Endpoint        string // 例如 https://s3.amazonaws.com 或 http://localhost:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool   // 使用 <endpoint>/<bucket>/<key> 形式的地址，MinIO 需要开启
+	PublicURL       string // 访问地址前缀，为空时与请求地址相同
+}
+
+// S3Storage 使用标准库实现的 S3 兼容存储，请求使用 SigV4 签名
+type S3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	publicURL string
+	signer    *sigV4Signer
+	client    *http.Client
+}
+
+// NewS3Storage 校验配置，不会发起网络请求
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage: s3 bucket is required")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	s := &S3Storage{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		signer:    &sigV4Signer{accessKey: cfg.AccessKeyID, secretKey: cfg.SecretAccessKey, region: region},
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}
+	s.publicURL = cfg.PublicURL
+	if s.publicURL == "" {
+		s.publicURL = strings.TrimSuffix(s.objectURL("").String(), "/")
+	}
+	return s, nil
+}
+
+// objectURL 返回对象的请求地址
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		u.Path = base + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+// do 发送签名请求，非 2xx 响应转换为错误
+func (s *S3Storage) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		reader = bytes.NewReader(body)
+		payloadHash = sha256Hex(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+	}
+	s.signer.Sign(req, payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: s3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// Put 需要计算请求体的哈希，会把内容读入内存，适合图片等较小的文件
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, body, header)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// PresignPut 请求体大小通过签名的 Content-Length 限制，浏览器会按文件大小自动设置该请求头
+func (s *S3Storage) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	now := time.Now()
+	return &PresignedRequest{
+		Method:    http.MethodPut,
+		URL:       s.signer.Presign(req, expires, now),
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: now.Add(expires),
+	}, nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "test-secret-key"
+	testBucket    = "travel"
+)
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+// fakeS3 内存中的 S3 替身，按收到的原始请求重新计算 SigV4 签名，签名不一致时返回 403
+type fakeS3 struct {
+	*httptest.Server
+	signer *sigV4Signer
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+	f := &fakeS3{
+		signer:  &sigV4Signer{accessKey: testAccessKey, secretKey: testSecretKey, region: "us-east-1"},
+		objects: make(map[string]fakeObject),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.verify(r, body); err != nil {
+		http.Error(w, "SignatureDoesNotMatch: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = fakeObject{data: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Write(obj.data)
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify 支持 Authorization 头和查询参数两种签名方式
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	query := r.URL.Query()
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+
+	if query.Get("X-Amz-Signature") != "" {
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		amzDate = query.Get("X-Amz-Date")
+		payloadHash = unsignedPayload
+		query.Del("X-Amz-Signature")
+
+		signedAt, err := time.Parse(sigV4DateFormat, amzDate)
+		if err != nil {
+			return err
+		}
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil {
+			return err
+		}
+		if time.Now().After(signedAt.Add(time.Duration(expires) * time.Second)) {
+			return errors.New("request has expired")
+		}
+	} else {
+		auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), sigV4Algorithm+" ")
+		if !ok {
+			return errors.New("missing authorization")
+		}
+		for _, part := range strings.Split(auth, ", ") {
+			name, value, _ := strings.Cut(part, "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		if payloadHash != sha256Hex(body) {
+			return errors.New("x-amz-content-sha256 does not match the body")
+		}
+		if !strings.Contains(";"+signedHeaders+";", ";x-amz-date;") {
+			return errors.New("x-amz-date is not signed")
+		}
+	}
+
+	signedAt, err := time.Parse(sigV4DateFormat, amzDate)
+	if err != nil {
+		return err
+	}
+	if want := testAccessKey + "/" + f.signer.scope(signedAt); credential != want {
+		return fmt.Errorf("credential %q, want %q", credential, want)
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = strconv.FormatInt(r.ContentLength, 10)
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	if !strings.Contains(";"+signedHeaders+";", ";host;") {
+		return errors.New("host is not signed")
+	}
+	if r.Header.Get("Content-Type") != "" && !strings.Contains(";"+signedHeaders+";", ";content-type;") {
+		return errors.New("content-type is not signed")
+	}
+
+	// 使用请求实际发送的编码路径，确认客户端签名的路径与线上传输的一致
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQuery(query),
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	want := f.signer.signature(signedAt, f.signer.stringToSign(signedAt, canonicalRequest))
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+func newTestS3Storage(t *testing.T, endpoint, secret string) *S3Storage {
+	t.Helper()
+	s, err := NewS3Storage(S3Config{
+		Endpoint:        endpoint,
+		Bucket:          testBucket,
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: secret,
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return s
+}
+
+func TestS3StoragePutGetDelete(t *testing.T) {
+	fake := newFakeS3(t)
+	s := newTestS3Storage(t, fake.URL, testSecretKey)
+	ctx := context.Background()
+
+	// 包含需要转义的字符，确认签名路径与实际请求路径一致
+	key := "images/2024/01/a b+c(1).jpg"
+	data := []byte("image data")
+	if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	obj, ok := fake.object(key)
+	if !ok || !bytes.Equal(obj.data, data) || obj.contentType != "image/jpeg" {
+		t.Fatalf("stored object = %+v, %v", obj, ok)
+	}
+
+	body, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Get = %q, want %q", got, data)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete: %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete missing object: %v", err)
+	}
+
+	if got, want := s.URL(key), fake.URL+"/"+testBucket+"/"+key; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+}
+
+func TestS3StorageWrongSecret(t *testing.T) {
+	fake := newFakeS3(t)
+	s := newTestS3Storage(t, fake.URL, "wrong-secret")
+
+	err := s.Put(context.Background(), "images/a.jpg", strings.NewReader("x"), 1, "image/jpeg")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Put with wrong secret: %v, want 403", err)
+	}
+}
+
+func TestS3StoragePresignPut(t *testing.T) {
+	fake := newFakeS3(t)
+	s := newTestS3Storage(t, fake.URL, testSecretKey)
+	ctx := context.Background()
+
+	upload := func(signed *PresignedRequest, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(signed.Method, signed.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range signed.Headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	data := "0123456789"
+	signed, err := s.PresignPut(ctx, "images/direct.png", "image/png", int64(len(data)), time.Minute)
+	if err != nil {
+		t.Fatalf("PresignPut: %v", err)
+	}
+
+	// 签名限制了请求体大小
+	if status := upload(signed, data+"extra"); status != http.StatusForbidden {
+		t.Fatalf("upload with a different size: status %d, want 403", status)
+	}
+	if status := upload(signed, data); status != http.StatusOK {
+		t.Fatalf("upload: status %d", status)
+	}
+	if obj, ok := fake.object("images/direct.png"); !ok || string(obj.data) != data || obj.contentType != "image/png" {
+		t.Fatalf("stored object = %+v, %v", obj, ok)
+	}
+
+	expired, err := s.PresignPut(ctx, "images/late.png", "image/png", int64(len(data)), 0)
+	if err != nil {
+		t.Fatalf("PresignPut: %v", err)
+	}
+	if status := upload(expired, data); status != http.StatusForbidden {
+		t.Fatalf("upload after expiry: status %d, want 403", status)
+	}
+}
+
+func TestS3StorageVirtualHostedURL(t *testing.T) {
+	s, err := NewS3Storage(S3Config{Endpoint: "https://s3.example.com", Bucket: testBucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.objectURL("images/a b.jpg").String(), "https://travel.s3.example.com/images/a%20b.jpg"; got != want {
+		t.Errorf("objectURL = %q, want %q", got, want)
+	}
+	if got, want := s.URL("images/a.jpg"), "https://travel.s3.example.com/images/a.jpg"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version 4，只实现 S3 对象接口需要的部分
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4DateFormat  = "20060102T150405Z"
+	sigV4Service     = "s3"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+)
+
+type sigV4Signer struct {
+	accessKey string
+	secretKey string
+	region    string
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncode 按 SigV4 规则编码，除 A-Z a-z 0-9 - _ . ~ 外全部转义，encodeSlash 为 false 时保留 /
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString("%")
+			b.WriteString(strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// canonicalHeaders 返回规范化的请求头和参与签名的请求头名称列表
+func canonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "content-length" || name == "content-md5" || name == "date" || name == "range" {
+			headers[name] = strings.Join(v, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString(":")
+		b.WriteString(strings.TrimSpace(headers[name]))
+		b.WriteString("\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+func (s *sigV4Signer) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region + "/" + sigV4Service + "/aws4_request"
+}
+
+func (s *sigV4Signer) signature(t time.Time, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, sigV4Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *sigV4Signer) stringToSign(t time.Time, canonicalRequest string) string {
+	return sigV4Algorithm + "\n" +
+		t.Format(sigV4DateFormat) + "\n" +
+		s.scope(t) + "\n" +
+		sha256Hex([]byte(canonicalRequest))
+}
+
+// Sign 使用 Authorization 头签名请求，payloadHash 为请求体的 SHA-256 或 UNSIGNED-PAYLOAD
+func (s *sigV4Signer) Sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(sigV4DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers, signedHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	signature := s.signature(t, s.stringToSign(t, canonicalRequest))
+	req.Header.Set("Authorization", sigV4Algorithm+
+		" Credential="+s.accessKey+"/"+s.scope(t)+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
+
+// Presign 把签名放在查询参数中，返回的地址在 expires 内可以直接访问。
+// req 中参与签名的请求头，客户端发送请求时必须带上相同的值
+func (s *sigV4Signer) Presign(req *http.Request, expires time.Duration, t time.Time) string {
+	t = t.UTC()
+	headers, signedHeaders := canonicalHeaders(req)
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(sigV4DateFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(query),
+		headers,
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	signature := s.signature(t, s.stringToSign(t, canonicalRequest))
+	u := *req.URL
+	u.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return u.String()
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey 对象键为空、是绝对路径或包含 ..
+var ErrInvalidKey = errors.New("storage: invalid object key")
+
+// Storage 对象存储接口，上传的图片等文件都通过它读写
+// key 使用 / 分隔，例如 images/2024/01/02/xxx.jpg
+type Storage interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// URL 返回对象的公开访问地址
+	URL(key string) string
+}
+
+// PresignedRequest 客户端直传对象时发送的请求，Headers 中的请求头需要原样带上
+type PresignedRequest struct {
+	Method    string
+	URL       string
+	Headers   map[string]string
+	ExpiresAt time.Time
+}
+
+// Presigner 支持客户端直传的存储实现，本地存储不支持
+type Presigner interface {
+	// PresignPut 生成在 expires 内有效的上传地址，上传时必须使用 contentType，
+	// 存储支持时还会限制请求体大小必须为 size
+	PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error)
+}
+
+// CleanKey 校验并规范化对象键，防止越过存储根目录
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// joinURL 拼接访问地址前缀和对象键
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -0,0 +1,81 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 密钥 "12345678901234567890"
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// RFC 6238 附录 B 的 SHA1 测试向量，8 位验证码取后 6 位
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestCodeAtRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", v.unix, err)
+		}
+		if got != v.code {
+			t.Errorf("CodeAt(%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCodeAtAcceptsLowercaseSecret(t *testing.T) {
+	got, err := CodeAt(" "+strings.ToLower(rfcSecret)+" ", Step(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Fatalf("CodeAt with lowercase secret = %q, %v", got, err)
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Fatal("CodeAt accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	if step, ok := Validate(rfcSecret, "050471", now, 1); !ok || step != current {
+		t.Fatalf("Validate(current) = %d, %v, want %d, true", step, ok, current)
+	}
+	// 1111111109 与 1111111111 在同一个时间步之前一步
+	if step, ok := Validate(rfcSecret, "081804", now, 1); !ok || step != current-1 {
+		t.Fatalf("Validate(previous step) = %d, %v, want %d, true", step, ok, current-1)
+	}
+	if _, ok := Validate(rfcSecret, "081804", now, 0); ok {
+		t.Fatal("previous step accepted without skew")
+	}
+
+	later := now.Add(3 * Period * time.Second)
+	if _, ok := Validate(rfcSecret, "050471", later, 1); ok {
+		t.Fatal("code accepted outside the skew window")
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("Validate(%q) accepted", code)
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	got := ProvisioningURI("Travel Guide", "alice@example.com", rfcSecret)
+	want := "otpauth://totp/Travel%20Guide:alice@example.com?algorithm=SHA1&digits=6&issuer=Travel%20Guide&period=30&secret=" + rfcSecret
+	if got != want {
+		t.Fatalf("ProvisioningURI = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors 按字段收集的校验错误
+type Errors []FieldError
+
+// Add 追加一个字段错误
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Has 是否存在错误
+func (e Errors) Has() bool {
+	return len(e) > 0
+}
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 50
+	NicknameMaxLength = 100
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+
+// Email 校验邮箱格式，只接受不带显示名的地址
+func Email(email string) string {
+	if len(email) > 255 {
+		return "邮箱不能超过255个字符"
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "邮箱格式不正确"
+	}
+	return ""
+}
+
+// Phone 校验手机号，允许带 + 的国际区号
+func Phone(phone string) string {
+	if !phonePattern.MatchString(phone) {
+		return "手机号格式不正确"
+	}
+	return ""
+}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// Username 校验用户名：字母开头，只包含字母、数字和下划线，且不能是保留名
+func Username(username string, reserved []string) string {
+	if username == "" {
+		return "用户名不能为空"
+	}
+	if len(username) < UsernameMinLength || len(username) > UsernameMaxLength {
+		return fmt.Sprintf("用户名长度需在%d到%d个字符之间", UsernameMinLength, UsernameMaxLength)
+	}
+	if !usernamePattern.MatchString(username) {
+		return "用户名只能包含字母、数字和下划线，且必须以字母开头"
+	}
+	for _, name := range reserved {
+		if strings.EqualFold(username, name) {
+			return "该用户名为系统保留，不能使用"
+		}
+	}
+	return ""
+}
+
+// Nickname 校验昵称长度，空昵称由调用方回退为用户名
+func Nickname(nickname string) string {
+	if utf8.RuneCountInString(nickname) > NicknameMaxLength {
+		return fmt.Sprintf("昵称不能超过%d个字符", NicknameMaxLength)
+	}
+	return ""
+}
+
+// PasswordPolicy 密码强度要求
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Password 按策略校验密码强度
+func Password(password string, policy PasswordPolicy) string {
+	if password == "" {
+		return "密码不能为空"
+	}
+	// bcrypt 只使用前 72 个字节
+	if len(password) > 72 {
+		return "密码不能超过72个字节"
+	}
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Sprintf("密码长度不能少于%d位", policy.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if policy.RequireUpper && !hasUpper {
+		missing = append(missing, "大写字母")
+	}
+	if policy.RequireLower && !hasLower {
+		missing = append(missing, "小写字母")
+	}
+	if policy.RequireDigit && !hasDigit {
+		missing = append(missing, "数字")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return "密码必须包含" + strings.Join(missing, "、")
+	}
+	return ""
+}
@@ -52,12 +52,31 @@ export interface CreateGuideResponse {
   message: string;
 }
 
+// 图片的一个尺寸
+export interface ImageVariant {
+  url: string;
+  width?: number;
+  height?: number;
+}
+
+// 图文中的图片，variants 固定包含 thumbnail、medium、large
+export interface GuideImage {
+  id?: number;
+  image_id?: number;
+  url: string;
+  width?: number;
+  height?: number;
+  placeholder?: string;
+  caption?: string;
+  variants: Record<'thumbnail' | 'medium' | 'large', ImageVariant>;
+}
+
 // 图文列表项
 export interface GuideItem {
   id: number;
   title: string;
   content: string;
-  images: string[];
+  images: GuideImage[];
   tags: Tag[];
   user: {
     id: number;